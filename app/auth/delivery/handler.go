@@ -0,0 +1,81 @@
+package authdelivery
+
+import (
+	"net/http"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	UseCase domain.AuthUseCase
+}
+
+func NewHandler(useCase domain.AuthUseCase) *Handler {
+	return &Handler{UseCase: useCase}
+}
+
+func (handler *Handler) SetPassword(ctx echo.Context) error {
+	var password domain.Password
+
+	if err := ctx.Bind(&password); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctx.Validate(&password); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	nickname := ctx.Param("nickname")
+	err := handler.UseCase.SetPassword(nickname, password.Password, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (handler *Handler) IssueToken(ctx echo.Context) error {
+	var credentials domain.Credentials
+
+	if err := ctx.Bind(&credentials); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctx.Validate(&credentials); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	token, err := handler.UseCase.IssueToken(credentials, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.BadCredentials {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, token)
+}
+
+func (handler *Handler) RevokeToken(ctx echo.Context) error {
+	if middleware.CurrentActor(ctx).Nickname == "" {
+		return ctx.JSON(http.StatusUnauthorized, domain.CustomError{Message: domain.Unauthorized})
+	}
+
+	err := handler.UseCase.RevokeToken(middleware.BearerToken(ctx))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package authrepository
+
+import (
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/jackc/pgx"
+)
+
+type Repository struct {
+	db *pgx.ConnPool
+}
+
+func NewRepository(db *pgx.ConnPool) *Repository {
+	return &Repository{db: db}
+}
+
+func (repository *Repository) GetPasswordHash(nickname string) ([]byte, error) {
+	var hash []byte
+	err := repository.db.QueryRow(`SELECT hash FROM user_password WHERE nickname=$1`, nickname).Scan(&hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash, nil
+}
+
+func (repository *Repository) SetPasswordHash(nickname string, hash []byte) error {
+	_, err := repository.db.Exec(`INSERT INTO user_password (nickname, hash) VALUES ($1, $2) 
+		ON CONFLICT (nickname) DO UPDATE SET hash=EXCLUDED.hash, updated=NOW()`, nickname, hash)
+	return err
+}
+
+func (repository *Repository) AddToken(hash []byte, nickname string, expires time.Time) (domain.Token, error) {
+	var result domain.Token
+	row := repository.db.QueryRow(`INSERT INTO auth_token (hash, nickname, expires) 
+		VALUES ($1, COALESCE((SELECT nickname FROM users WHERE nickname=$2), $2), $3) 
+		RETURNING nickname, expires`, hash, nickname, expires)
+
+	err := row.Scan(&result.Nickname, &result.Expires)
+	if err != nil {
+		return domain.Token{}, err
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetTokenOwner(hash []byte) (string, error) {
+	var nickname string
+	err := repository.db.QueryRow(`SELECT nickname FROM auth_token WHERE hash=$1 AND expires > NOW()`, hash).
+		Scan(&nickname)
+	if err != nil {
+		return "", err
+	}
+
+	return nickname, nil
+}
+
+func (repository *Repository) DeleteToken(hash []byte) error {
+	_, err := repository.db.Exec(`DELETE FROM auth_token WHERE hash=$1`, hash)
+	return err
+}
@@ -0,0 +1,102 @@
+package authusecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/jackc/pgx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const tokenBytes = 32
+
+type UseCase struct {
+	Repository domain.AuthRepository
+	TokenTTL   time.Duration
+}
+
+func NewUseCase(repository domain.AuthRepository, tokenTTL time.Duration) *UseCase {
+	return &UseCase{Repository: repository, TokenTTL: tokenTTL}
+}
+
+// SetPassword lets an authenticated user or an admin set a password. Accounts
+// created without one get it from an admin or through an admin-issued token.
+func (uc *UseCase) SetPassword(nickname string, password string, actor domain.Actor) *domain.CustomError {
+	if !actor.Admin && !actor.Is(nickname) {
+		return &domain.CustomError{Message: domain.Forbidden}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	err = uc.Repository.SetPasswordHash(nickname, hash)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return &domain.CustomError{Message: domain.NoUser}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+// IssueToken trades a nickname and password for a bearer token. Admins may
+// issue tokens for any user without a password.
+func (uc *UseCase) IssueToken(credentials domain.Credentials, actor domain.Actor) (domain.Token, *domain.CustomError) {
+	if !actor.Admin {
+		hash, err := uc.Repository.GetPasswordHash(credentials.Nickname)
+		if err != nil && err != pgx.ErrNoRows {
+			return domain.Token{}, &domain.CustomError{Message: err.Error()}
+		}
+		if err == pgx.ErrNoRows || bcrypt.CompareHashAndPassword(hash, []byte(credentials.Password)) != nil {
+			return domain.Token{}, &domain.CustomError{Message: domain.BadCredentials}
+		}
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return domain.Token{}, &domain.CustomError{Message: err.Error()}
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	token, err := uc.Repository.AddToken(hashToken(secret), credentials.Nickname, time.Now().Add(uc.TokenTTL))
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return domain.Token{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Token{}, &domain.CustomError{Message: err.Error()}
+	}
+	token.Token = secret
+
+	return token, nil
+}
+
+func (uc *UseCase) Authenticate(token string) (string, *domain.CustomError) {
+	nickname, err := uc.Repository.GetTokenOwner(hashToken(token))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", &domain.CustomError{Message: domain.Unauthorized}
+		}
+		return "", &domain.CustomError{Message: err.Error()}
+	}
+
+	return nickname, nil
+}
+
+func (uc *UseCase) RevokeToken(token string) *domain.CustomError {
+	if err := uc.Repository.DeleteToken(hashToken(token)); err != nil {
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
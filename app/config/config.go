@@ -0,0 +1,342 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/ratelimit"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvPrefix     = "FORUM_"
+	EnvConfigPath = EnvPrefix + "CONFIG"
+	RedactedValue = "******"
+)
+
+type Database struct {
+	User           string        `yaml:"user"`
+	Name           string        `yaml:"name"`
+	Password       string        `yaml:"password"`
+	Host           string        `yaml:"host"`
+	Port           int           `yaml:"port"`
+	MaxConnections int           `yaml:"max_connections"`
+	AcquireTimeout time.Duration `yaml:"acquire_timeout"`
+}
+
+type Server struct {
+	Address         string        `yaml:"address"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	ReadyTimeout    time.Duration `yaml:"ready_timeout"`
+	CursorSecret    string        `yaml:"cursor_secret"`
+	AdminToken      string        `yaml:"admin_token"`
+	RequireAuth     bool          `yaml:"require_auth"`
+	TokenTTL        time.Duration `yaml:"token_ttl"`
+	MaxBodyBytes    int           `yaml:"max_body_bytes"`
+	MaxBatchPosts   int           `yaml:"max_batch_posts"`
+	Reactions       string        `yaml:"reactions"`
+	TrustedProxies  string        `yaml:"trusted_proxies"`
+}
+
+// RateLimit holds per-route limits as "N/duration"; empty disables one.
+type RateLimit struct {
+	Posts   string `yaml:"posts"`
+	Threads string `yaml:"threads"`
+	Votes   string `yaml:"votes"`
+}
+
+// Config is resolved with the precedence defaults < file < environment < flags.
+type Config struct {
+	Database  Database  `yaml:"database"`
+	Server    Server    `yaml:"server"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+
+	ConfigPath  string   `yaml:"-"`
+	PrintConfig bool     `yaml:"-"`
+	Args        []string `yaml:"-"`
+}
+
+type field struct {
+	name  string
+	env   string
+	usage string
+	value interface{}
+}
+
+func Default() Config {
+	return Config{
+		Database: Database{
+			User:           "kostya",
+			Name:           "kostya",
+			Password:       "kostya",
+			Host:           "127.0.0.1",
+			Port:           5432,
+			MaxConnections: 100,
+			AcquireTimeout: 0,
+		},
+		Server: Server{
+			Address:         ":5000",
+			ReadTimeout:     0,
+			WriteTimeout:    0,
+			IdleTimeout:     0,
+			ShutdownTimeout: 30 * time.Second,
+			ReadyTimeout:    2 * time.Second,
+			TokenTTL:        30 * 24 * time.Hour,
+			MaxBodyBytes:    8 << 20,
+			MaxBatchPosts:   10000,
+			Reactions:       "👍,👎,❤️,😄,🎉,😕,🚀,👀",
+		},
+	}
+}
+
+func (cfg *Config) fields() []field {
+	return []field{
+		{"db-user", "DB_USER", "database user", &cfg.Database.User},
+		{"db-name", "DB_NAME", "database name", &cfg.Database.Name},
+		{"db-password", "DB_PASSWORD", "database password", &cfg.Database.Password},
+		{"db-host", "DB_HOST", "database host", &cfg.Database.Host},
+		{"db-port", "DB_PORT", "database port", &cfg.Database.Port},
+		{"db-max-connections", "DB_MAX_CONNECTIONS", "connection pool size", &cfg.Database.MaxConnections},
+		{"db-acquire-timeout", "DB_ACQUIRE_TIMEOUT", "max wait for a pool connection (0 means no timeout)", &cfg.Database.AcquireTimeout},
+		{"listen", "LISTEN_ADDR", "http listen address", &cfg.Server.Address},
+		{"read-timeout", "READ_TIMEOUT", "http read timeout", &cfg.Server.ReadTimeout},
+		{"write-timeout", "WRITE_TIMEOUT", "http write timeout", &cfg.Server.WriteTimeout},
+		{"idle-timeout", "IDLE_TIMEOUT", "http keep-alive idle timeout", &cfg.Server.IdleTimeout},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long to drain in-flight requests on SIGINT/SIGTERM", &cfg.Server.ShutdownTimeout},
+		{"ready-timeout", "READY_TIMEOUT", "deadline for the database check behind /readyz", &cfg.Server.ReadyTimeout},
+		{"cursor-secret", "CURSOR_SECRET", "hmac key for page cursors (random per process when empty)", &cfg.Server.CursorSecret},
+		{"admin-token", "ADMIN_TOKEN", "shared token for operator endpoints (disabled when empty)", &cfg.Server.AdminToken},
+		{"require-auth", "REQUIRE_AUTH", "reject anonymous writes", &cfg.Server.RequireAuth},
+		{"token-ttl", "TOKEN_TTL", "lifetime of issued api tokens", &cfg.Server.TokenTTL},
+		{"max-body-bytes", "MAX_BODY_BYTES", "largest accepted request body (0 means unlimited)", &cfg.Server.MaxBodyBytes},
+		{"max-batch-posts", "MAX_BATCH_POSTS", "most posts accepted in one create request (0 means unlimited)", &cfg.Server.MaxBatchPosts},
+		{"reactions", "REACTIONS", "comma separated emoji allowed as post reactions", &cfg.Server.Reactions},
+		{"trusted-proxies", "TRUSTED_PROXIES", "comma separated CIDRs whose X-Forwarded-For is trusted (empty uses the peer address)", &cfg.Server.TrustedProxies},
+		{"rate-posts", "RATE_POSTS", "posts created per caller, as N/duration", &cfg.RateLimit.Posts},
+		{"rate-threads", "RATE_THREADS", "thread creations per forum, as N/duration", &cfg.RateLimit.Threads},
+		{"rate-votes", "RATE_VOTES", "votes per caller, as N/duration", &cfg.RateLimit.Votes},
+	}
+}
+
+func (cfg *Config) flagSet(output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("forum", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&cfg.ConfigPath, "config", cfg.ConfigPath, "path to a yaml config file (env "+EnvConfigPath+")")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the resolved config with secrets redacted and exit")
+	for _, f := range cfg.fields() {
+		usage := f.usage + " (env " + EnvPrefix + f.env + ")"
+		switch value := f.value.(type) {
+		case *string:
+			fs.StringVar(value, f.name, *value, usage)
+		case *int:
+			fs.IntVar(value, f.name, *value, usage)
+		case *bool:
+			fs.BoolVar(value, f.name, *value, usage)
+		case *time.Duration:
+			fs.DurationVar(value, f.name, *value, usage)
+		}
+	}
+	return fs
+}
+
+func Load(args []string) (Config, error) {
+	probe := Default()
+	probe.ConfigPath = os.Getenv(EnvConfigPath)
+	if err := probe.flagSet(ioutil.Discard).Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			probe.flagSet(os.Stderr).Usage()
+		}
+		return Config{}, err
+	}
+
+	cfg := Default()
+	cfg.ConfigPath = probe.ConfigPath
+	if cfg.ConfigPath != "" {
+		if err := cfg.loadFile(cfg.ConfigPath); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	fs := cfg.flagSet(ioutil.Discard)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	cfg.Args = fs.Args()
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) loadEnv() error {
+	for _, f := range cfg.fields() {
+		raw, ok := os.LookupEnv(EnvPrefix + f.env)
+		if !ok {
+			continue
+		}
+		switch value := f.value.(type) {
+		case *string:
+			*value = raw
+		case *int:
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("config: %s%s: %w", EnvPrefix, f.env, err)
+			}
+			*value = parsed
+		case *bool:
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("config: %s%s: %w", EnvPrefix, f.env, err)
+			}
+			*value = parsed
+		case *time.Duration:
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("config: %s%s: %w", EnvPrefix, f.env, err)
+			}
+			*value = parsed
+		}
+	}
+	return nil
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Database.User == "" {
+		return errors.New("config: database user is required")
+	}
+	if cfg.Database.Name == "" {
+		return errors.New("config: database name is required")
+	}
+	if cfg.Database.Host == "" {
+		return errors.New("config: database host is required")
+	}
+	if cfg.Database.Port <= 0 || cfg.Database.Port > 65535 {
+		return fmt.Errorf("config: database port %d is out of range", cfg.Database.Port)
+	}
+	if cfg.Database.MaxConnections < 2 {
+		return fmt.Errorf("config: max connections must be at least 2, got %d", cfg.Database.MaxConnections)
+	}
+	if cfg.Server.Address == "" {
+		return errors.New("config: listen address is required")
+	}
+	if cfg.Server.ReadyTimeout == 0 {
+		return errors.New("config: ready timeout must be positive")
+	}
+	if cfg.Server.TokenTTL == 0 {
+		return errors.New("config: token ttl must be positive")
+	}
+	if cfg.Server.MaxBodyBytes < 0 {
+		return errors.New("config: max body bytes must not be negative")
+	}
+	if cfg.Server.MaxBatchPosts < 0 {
+		return errors.New("config: max batch posts must not be negative")
+	}
+	if len(cfg.Server.ReactionSet()) == 0 {
+		return errors.New("config: at least one reaction is required")
+	}
+	if _, err := cfg.Server.TrustedProxyRanges(); err != nil {
+		return fmt.Errorf("config: trusted proxies: %w", err)
+	}
+	for _, f := range cfg.fields() {
+		if value, ok := f.value.(*time.Duration); ok && *value < 0 {
+			return fmt.Errorf("config: %s must not be negative", f.name)
+		}
+	}
+	for _, raw := range []string{cfg.RateLimit.Posts, cfg.RateLimit.Threads, cfg.RateLimit.Votes} {
+		if _, err := ratelimit.ParseLimit(raw); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReactionSet splits the reactions list, dropping blanks and surrounding
+// spaces.
+func (server Server) ReactionSet() []string {
+	var set []string
+	for _, emoji := range strings.Split(server.Reactions, ",") {
+		if emoji = strings.TrimSpace(emoji); emoji != "" {
+			set = append(set, emoji)
+		}
+	}
+	return set
+}
+
+// TrustedProxyRanges parses the trusted proxies list; plain addresses are
+// taken as single-host ranges.
+func (server Server) TrustedProxyRanges() ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, raw := range strings.Split(server.TrustedProxies, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		if !strings.Contains(raw, "/") {
+			ip := net.ParseIP(raw)
+			if ip == nil {
+				return nil, fmt.Errorf("bad address %q", raw)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipRange, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, ipRange)
+	}
+	return ranges, nil
+}
+
+func (cfg Config) Redacted() Config {
+	if cfg.Database.Password != "" {
+		cfg.Database.Password = RedactedValue
+	}
+	if cfg.Server.CursorSecret != "" {
+		cfg.Server.CursorSecret = RedactedValue
+	}
+	if cfg.Server.AdminToken != "" {
+		cfg.Server.AdminToken = RedactedValue
+	}
+	return cfg
+}
+
+func (cfg Config) Print(w io.Writer) error {
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Actor is the caller a write is attributed to. An anonymous actor has an
+// empty Nickname and is only let through while authentication is optional.
+type Actor struct {
+	Nickname string
+	Admin    bool
+}
+
+// ActFor decides whether the actor may write as nickname. Anonymous callers
+// pass while authentication is optional and are Unauthorized once it is
+// required; an authenticated caller writing as someone else is Forbidden.
+func (actor Actor) ActFor(nickname string, requireAuth bool) *CustomError {
+	if actor.Admin || actor.Is(nickname) {
+		return nil
+	}
+	if actor.Nickname == "" {
+		if requireAuth {
+			return &CustomError{Message: Unauthorized}
+		}
+		return nil
+	}
+	return &CustomError{Message: Forbidden}
+}
+
+// Is reports whether the actor is authenticated as nickname.
+func (actor Actor) Is(nickname string) bool {
+	return actor.Nickname != "" && strings.EqualFold(actor.Nickname, nickname)
+}
+
+type Credentials struct {
+	Nickname string `json:"nickname" validate:"required"`
+	Password string `json:"password"`
+}
+
+type Password struct {
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type Token struct {
+	Token    string    `json:"token"`
+	Nickname string    `json:"nickname"`
+	Expires  time.Time `json:"expires"`
+}
+
+type AuthRepository interface {
+	GetPasswordHash(nickname string) ([]byte, error)
+	SetPasswordHash(nickname string, hash []byte) error
+	AddToken(hash []byte, nickname string, expires time.Time) (Token, error)
+	GetTokenOwner(hash []byte) (string, error)
+	DeleteToken(hash []byte) error
+}
+
+type AuthUseCase interface {
+	SetPassword(nickname string, password string, actor Actor) *CustomError
+	IssueToken(credentials Credentials, actor Actor) (Token, *CustomError)
+	Authenticate(token string) (string, *CustomError)
+	RevokeToken(token string) *CustomError
+}
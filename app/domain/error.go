@@ -5,14 +5,47 @@ const (
 	NoUser       = "Can't find user\n"
 	BadParentPost = "Parent post was created in another thread\n"
 	ConflictData = "Conflict data\n"
+	SchemaMismatch = "Database schema version mismatch\n"
+	BadStatusMode = "Unknown status mode\n"
+	BadCursor = "Bad cursor\n"
+	EmptySearchQuery = "Empty search query\n"
+	BadSearchType = "Unknown search type\n"
+	BadSearchLimit = "Search limit must be positive\n"
+	NoPost = "Can't find post\n"
+	Forbidden = "Forbidden\n"
+	ThreadArchived = "Thread is archived\n"
+	ThreadLocked = "Thread is locked\n"
+	BadThreadStatus = "Unknown thread status\n"
+	Unauthorized = "Unauthorized\n"
+	BadCredentials = "Bad nickname or password\n"
+	BadRole = "Unknown role\n"
+	UserBanned = "User is banned\n"
+	UserMuted = "User is muted in this forum\n"
+	BadExpiry = "Expiry must be in the future\n"
+	NoSanction = "Can't find sanction\n"
+	TooManyRequests = "Too many requests\n"
+	RequestTooLarge = "Request body too large\n"
+	TooManyPosts = "Too many posts in one batch\n"
+	BadBatchMode = "Unknown batch mode\n"
+	InvalidPosts = "Some posts in the batch are invalid\n"
+	NoVote = "Can't find vote\n"
+	BadReaction = "Unknown reaction\n"
+	NoReaction = "Can't find reaction\n"
+	NoRevision = "Can't find revision\n"
 )
 
 const (
 	PgxBadParentErrorCode = "77777"
 	PgxNoFoundFieldErrorCode = "23503"
 	PgxUniqErrorCode         = "23505"
+	PgxSerializationErrorCode = "40001"
+	PgxDeadlockErrorCode = "40P01"
 )
 
 type CustomError struct {
 	Message string `json:"message"`
 }
+
+func (err *CustomError) Error() string {
+	return err.Message
+}
@@ -14,20 +14,33 @@ type Forum struct {
 	Threads int64  `json:"threads"`
 }
 
+const (
+	ThreadStatusOpen   = "open"
+	ThreadStatusLocked = "locked"
+)
+
 type Thread struct {
-	Id      int32     `json:"id"`
-	Title   string    `json:"title" validate:"required"`
-	Author  string    `json:"author" validate:"required"`
-	Forum   string    `json:"forum"`
-	Message string    `json:"message" validate:"required"`
-	Votes   int32     `json:"votes"`
-	Slug    string    `json:"slug,omitempty"`
-	Created time.Time `json:"created"`
+	Id       int32     `json:"id"`
+	Title    string    `json:"title" validate:"required"`
+	Author   string    `json:"author" validate:"required"`
+	Forum    string    `json:"forum"`
+	Message  string    `json:"message" validate:"required"`
+	Votes    int32     `json:"votes"`
+	Slug     string    `json:"slug,omitempty"`
+	Created  time.Time `json:"created"`
+	Archived bool      `json:"archived,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Pinned   bool      `json:"pinned,omitempty"`
+	PinOrder int32     `json:"pinOrder,omitempty"`
+}
+
+type Pin struct {
+	Order int32 `json:"order"`
 }
 
 type Vote struct {
 	NickName string `json:"nickname"`
-	Voice    int    `json:"voice"`
+	Voice    int    `json:"voice" validate:"oneof=-1 1"`
 }
 
 type ForumRepository interface {
@@ -37,37 +50,74 @@ type ForumRepository interface {
 	AddThread(thread Thread) (Thread, error)
 	GetUsersForum(slug string, filter tools.FilterUser) ([]User, error)
 	GetForumThreads(slug string, filter tools.FilterThread) ([]Thread, error)
+	GetPinnedThreads(slug string, includeArchived bool) ([]Thread, error)
+	GetForumRole(forum string, nickname string) (string, error)
+	GetSiteRole(nickname string) (string, error)
+	GetForumRoles(forum string) ([]Role, error)
+	SetForumRole(role Role) (Role, error)
+	DeleteForumRole(forum string, nickname string) error
+	SetSiteAdmin(nickname string, admin bool) error
 }
 
 type ForumUseCase interface {
-	CreateForum(forum Forum) (Forum, *CustomError)
+	CreateForum(forum Forum, actor Actor) (Forum, *CustomError)
 	GetDetailsForum(slug string) (Forum, *CustomError)
-	CreateThread(thread Thread) (Thread, *CustomError)
+	CreateThread(thread Thread, actor Actor) (Thread, *CustomError)
 	GetUsersForum(slug string, filter tools.FilterUser) ([]User, *CustomError)
 	GetForumThreads(slug string, filter tools.FilterThread) ([]Thread, *CustomError)
+	GetRoles(forum string) ([]Role, *CustomError)
+	GrantRole(role Role, actor Actor) (Role, *CustomError)
+	RevokeRole(forum string, nickname string, actor Actor) *CustomError
+	SetSiteAdmin(nickname string, admin bool) *CustomError
 }
 
 type ThreadRepository interface {
-	CreatePosts(threadId int, threadForum string, post []Post) ([]Post, error)
+	CreatePosts(slugOrId string, post []Post, partial bool, check func(Thread, *Sanction) error) ([]Post, []PostError, error)
 	GetThreadBySlug(slug string) (Thread, error)
 	GetThreadById(id int) (Thread, error)
 	GetThreadBySlugOrId(slugOrId string) (Thread, error)
 	CreateVoteBySlugOrId(slugOrId string, vote Vote) error
 	UpdateVoteBySlugOrId(slugOrId string, vote Vote) error
+	DeleteVote(threadId int, nickname string) error
+	GetVotes(threadId int, filter tools.FilterUser) ([]Vote, error)
 	GetPostById(id int) (Post, error)
-	UpdatePost(id int, post Post) (Post, error)
+	UpdatePost(id int, post Post, editor string) (Post, error)
+	GetPostHistory(id int) ([]PostRevision, error)
 	GetPostsFlatSlugOrId(slugOrId string, posts tools.FilterPosts) ([]*Post, error)
 	GetPostsTreeSlugOrId(slugOrId string, posts tools.FilterPosts) ([]*Post, error)
 	GetPostsParentTreeSlugOrId(slugOrId string, posts tools.FilterPosts) ([]*Post, error)
+	GetPostsTopSlugOrId(slugOrId string, posts tools.FilterPosts) ([]*Post, error)
+	VotePost(id int, vote Vote) (Post, error)
+	AddReaction(id int, reaction Reaction) error
+	DeleteReaction(id int, reaction Reaction) error
+	GetReactionCounts(ids []int64) (map[int64][]ReactionCount, error)
 	UpdateThread(slugOrId string, thread Thread) (Thread, error)
+	DeletePost(id int, editor string) (Post, error)
+	PurgePostSubtree(id int) (int64, error)
+	SetThreadArchived(slugOrId string, archived bool) (Thread, error)
+	SetThreadPinned(slugOrId string, pinned bool, order int32) (Thread, error)
+	DeleteThread(id int) error
 }
 
 type ThreadUseCase interface {
-	CreatePosts(slugOrId string, post []Post) ([]Post, *CustomError)
-	CreateVote(slugOrId string, vote Vote) (Thread, *CustomError)
+	CreatePosts(slugOrId string, post []Post, mode string, actor Actor) ([]Post, []PostError, *CustomError)
+	CreateVote(slugOrId string, vote Vote, actor Actor) (Thread, *CustomError)
+	RetractVote(slugOrId string, nickname string, actor Actor) (Thread, *CustomError)
+	GetVotes(slugOrId string, filter tools.FilterUser) ([]Vote, *CustomError)
 	GetThreadDetails(slugOrId string) (Thread, *CustomError)
 	GetPosts(slugOrId string, filter tools.FilterPosts) ([]*Post, *CustomError)
 	GetPost(id string, filter tools.FilterOnePost) (PostInfo, *CustomError)
-	UpdateThread(slugOrId string, thread Thread) (Thread, *CustomError)
-	UpdatePost(id string, post Post) (Post, *CustomError)
+	VotePost(id string, vote Vote, actor Actor) (Post, *CustomError)
+	AddReaction(id string, reaction Reaction, actor Actor) (Post, *CustomError)
+	RemoveReaction(id string, reaction Reaction, actor Actor) (Post, *CustomError)
+	UpdateThread(slugOrId string, thread Thread, actor Actor) (Thread, *CustomError)
+	UpdatePost(id string, post Post, actor Actor) (Post, *CustomError)
+	GetPostHistory(id string, actor Actor) ([]PostRevision, *CustomError)
+	DiffPost(id string, from string, to string, actor Actor) (PostDiff, *CustomError)
+	RevertPost(id string, revision int32, actor Actor) (Post, *CustomError)
+	DeletePost(id string, actor Actor) (Post, *CustomError)
+	PurgePost(id string, actor Actor) (PurgeResult, *CustomError)
+	ArchiveThread(slugOrId string, archived bool, actor Actor) (Thread, *CustomError)
+	PinThread(slugOrId string, pinned bool, order int32, actor Actor) (Thread, *CustomError)
+	DeleteThread(slugOrId string, actor Actor) *CustomError
 }
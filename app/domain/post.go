@@ -1,16 +1,80 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/tools"
+)
+
+const DeletedPostMessage = "[deleted]"
 
 type Post struct {
-	Id       int64     `json:"id"`
-	Parent   int64     `json:"parent"`
-	Author   string    `json:"author" validate:"required"`
-	Message  string    `json:"message" validate:"required"`
-	IsEdited bool      `json:"isEdited"`
-	Forum    string    `json:"forum"`
-	Thread   int32     `json:"thread"`
-	Created  time.Time `json:"created"`
+	Id        int64           `json:"id"`
+	Parent    int64           `json:"parent"`
+	Author    string          `json:"author" validate:"required"`
+	Message   string          `json:"message" validate:"required"`
+	IsEdited  bool            `json:"isEdited"`
+	IsDeleted bool            `json:"isDeleted,omitempty"`
+	Forum     string          `json:"forum"`
+	Thread    int32           `json:"thread"`
+	Created   time.Time       `json:"created"`
+	Score     int32           `json:"score"`
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+}
+
+type Reaction struct {
+	NickName string `json:"nickname"`
+	Emoji    string `json:"emoji" validate:"required"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int32  `json:"count"`
+}
+
+const (
+	BatchModeStrict  = "strict"
+	BatchModeReport  = "report"
+	BatchModePartial = "partial"
+)
+
+// PostError points at one post of a batch that was rejected.
+type PostError struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+type PostBatchError struct {
+	Message  string      `json:"message"`
+	Rejected []PostError `json:"rejected"`
+}
+
+type PostBatch struct {
+	Posts    []Post      `json:"posts"`
+	Rejected []PostError `json:"rejected"`
+}
+
+// PostRevision is the post as it read after an edit; revision 0 is the
+// original text.
+type PostRevision struct {
+	Revision int32     `json:"revision"`
+	Message  string    `json:"message"`
+	Editor   string    `json:"editor,omitempty"`
+	Edited   time.Time `json:"edited"`
+}
+
+type Revert struct {
+	Revision int32 `json:"revision"`
+}
+
+type PostDiff struct {
+	From  int32            `json:"from"`
+	To    int32            `json:"to"`
+	Lines []tools.DiffLine `json:"lines"`
+}
+
+type PurgeResult struct {
+	Purged int64 `json:"purged"`
 }
 
 type PostInfo struct {
@@ -19,3 +83,10 @@ type PostInfo struct {
 	Thread *Thread `json:"thread,omitempty"`
 	Forum  *Forum  `json:"forum,omitempty"`
 }
+
+func (post *Post) HideDeleted() {
+	if post.IsDeleted {
+		post.Author = ""
+		post.Message = DeletedPostMessage
+	}
+}
@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+const (
+	RoleBanned    = "banned"
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleBanned:    0,
+	RoleMember:    1,
+	RoleModerator: 2,
+	RoleOwner:     3,
+	RoleAdmin:     4,
+}
+
+type Role struct {
+	Forum    string    `json:"forum,omitempty"`
+	Nickname string    `json:"nickname"`
+	Role     string    `json:"role" validate:"required"`
+	Granted  time.Time `json:"granted"`
+}
+
+type RoleResolver interface {
+	GetForumRole(forum string, nickname string) (string, error)
+	GetSiteRole(nickname string) (string, error)
+}
+
+func RoleAtLeast(role string, min string) bool {
+	return roleRank[role] >= roleRank[min]
+}
+
+// RoleIn resolves the actor's effective role in a forum. The admin token
+// outranks everything and anonymous callers are plain members.
+func (actor Actor) RoleIn(roles RoleResolver, forum string) (string, error) {
+	if actor.Admin {
+		return RoleAdmin, nil
+	}
+	if actor.Nickname == "" {
+		return RoleMember, nil
+	}
+	return roles.GetForumRole(forum, actor.Nickname)
+}
+
+// SiteRole is RoleIn for actions that are not scoped to a forum.
+func (actor Actor) SiteRole(roles RoleResolver) (string, error) {
+	if actor.Admin {
+		return RoleAdmin, nil
+	}
+	if actor.Nickname == "" {
+		return RoleMember, nil
+	}
+	return roles.GetSiteRole(actor.Nickname)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+const (
+	SanctionBan  = "ban"
+	SanctionMute = "mute"
+)
+
+// Sanction is a global ban (no Forum) or a mute in one forum. A nil Expires
+// never lapses.
+type Sanction struct {
+	Id       int64      `json:"id"`
+	Kind     string     `json:"kind"`
+	Nickname string     `json:"nickname"`
+	Forum    string     `json:"forum,omitempty"`
+	Reason   string     `json:"reason"`
+	IssuedBy string     `json:"issuedBy,omitempty"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+func (sanction Sanction) Error() *CustomError {
+	if sanction.Kind == SanctionBan {
+		return &CustomError{Message: UserBanned}
+	}
+	return &CustomError{Message: UserMuted}
+}
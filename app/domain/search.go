@@ -0,0 +1,20 @@
+package domain
+
+import "github.com/Kostich31/techpark_db/app/tools"
+
+type SearchResult struct {
+	Type    string  `json:"type"`
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+	Post    *Post   `json:"post,omitempty"`
+	Thread  *Thread `json:"thread,omitempty"`
+}
+
+type SearchRepository interface {
+	SearchPosts(filter tools.FilterSearch) ([]SearchResult, error)
+	SearchThreads(filter tools.FilterSearch) ([]SearchResult, error)
+}
+
+type SearchUseCase interface {
+	Search(filter tools.FilterSearch) ([]SearchResult, *CustomError)
+}
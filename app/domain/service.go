@@ -1,5 +1,30 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
+const (
+	StatusModeExact    = "exact"
+	StatusModeEstimate = "estimate"
+	StatusModeCached   = "cached"
+)
+
+const (
+	HealthOk       = "ok"
+	HealthDegraded = "degraded"
+)
+
+const (
+	CheckForumPosts   = "forum.posts"
+	CheckForumThreads = "forum.threads"
+	CheckThreadVotes  = "thread.votes"
+	CheckVoteVoice    = "vote.voice"
+	CheckPostScore    = "post.score"
+	CheckUsersForum   = "users_forum"
+)
+
 type Status struct {
 	User int64 `json:"user,omitempty"`
 	Forum int64 `json:"forum,omitempty"`
@@ -7,12 +32,62 @@ type Status struct {
 	Post int64 `json:"post,omitempty"`
 }
 
+type PoolStat struct {
+	MaxConnections       int `json:"maxConnections"`
+	CurrentConnections   int `json:"currentConnections"`
+	AvailableConnections int `json:"availableConnections"`
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+type Health struct {
+	Status                string    `json:"status"`
+	Error                 string    `json:"error,omitempty"`
+	StartedAt             time.Time `json:"startedAt"`
+	Uptime                string    `json:"uptime"`
+	SchemaVersion         int       `json:"schemaVersion"`
+	ExpectedSchemaVersion int       `json:"expectedSchemaVersion"`
+	Pool                  PoolStat  `json:"pool"`
+	Build                 BuildInfo `json:"build"`
+}
+
+// Discrepancy is one trigger-maintained aggregate that disagrees with its
+// source rows. For users_forum, Stored and Actual are 1 or 0 for whether the
+// membership row exists and whether it should.
+type Discrepancy struct {
+	Check    string `json:"check"`
+	Forum    string `json:"forum,omitempty"`
+	Thread   int32  `json:"thread,omitempty"`
+	Post     int64  `json:"post,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+	Stored   int64  `json:"stored"`
+	Actual   int64  `json:"actual"`
+}
+
+type CheckReport struct {
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	Repaired      int64         `json:"repaired"`
+}
+
 type ServiceRepository interface {
 	GetStatus() (Status, error)
+	GetStatusEstimate() (Status, error)
+	GetStatusCached() (Status, error)
 	Clear() error
+	GetSchemaVersion(ctx context.Context) (int, error)
+	GetPoolStat() PoolStat
+	FindDiscrepancies() ([]Discrepancy, error)
+	Repair(check string, items []Discrepancy) (int64, error)
 }
 
 type ServiceUseCase interface {
-	GetStatus() (Status, error)
+	GetStatus(mode string) (Status, error)
 	Clear() error
+	Ready() *CustomError
+	GetHealth() Health
+	Check(repair bool, batch int) (CheckReport, error)
 }
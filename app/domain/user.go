@@ -5,6 +5,7 @@ type User struct {
 	FullName string `json:"fullname"`
 	About    string `json:"about"`
 	Email    string `json:"email"`
+	Password string `json:"password,omitempty" validate:"omitempty,min=8"`
 }
 
 type UserUpdate struct {
@@ -14,14 +15,22 @@ type UserUpdate struct {
 }
 
 type UserRepository interface {
-	AddUser(user User) (User, error)
+	AddUser(user User, passwordHash []byte) (User, error)
 	GetUser(nickname string) (User, error)
 	UpdateUser(user User) (User, error)
 	GetUsersByNicknameOrEmail(nickname string, email string) ([]User, error)
+	AddSanction(sanction Sanction) (Sanction, error)
+	GetSanction(id int64) (Sanction, error)
+	DeleteSanction(id int64) error
+	GetActiveSanctions(forum string) ([]Sanction, error)
+	GetActiveSanction(forum string, nicknames []string) (*Sanction, error)
 }
 
 type UserUseCase interface {
 	CreateUser(user User) ([]User, error)
 	GetUserProfile(nickname string) (User, *CustomError)
-	UpdateUserProfile(user User) (User, *CustomError)
+	UpdateUserProfile(user User, actor Actor) (User, *CustomError)
+	Sanction(sanction Sanction, actor Actor) (Sanction, *CustomError)
+	LiftSanction(id string, actor Actor) *CustomError
+	GetSanctions(forum string, actor Actor) ([]Sanction, *CustomError)
 }
@@ -2,18 +2,21 @@ package delivery
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/middleware"
 	"github.com/Kostich31/techpark_db/app/tools"
 	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
 	useCase domain.ForumUseCase
+	cursors *tools.CursorSigner
 }
 
-func NewHandler(useCase domain.ForumUseCase) *Handler {
-	return &Handler{useCase: useCase}
+func NewHandler(useCase domain.ForumUseCase, cursors *tools.CursorSigner) *Handler {
+	return &Handler{useCase: useCase, cursors: cursors}
 }
 
 func (handler *Handler) CreateForum(ctx echo.Context) error {
@@ -27,8 +30,14 @@ func (handler *Handler) CreateForum(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	forum, err := handler.useCase.CreateForum(newForum)
+	forum, err := handler.useCase.CreateForum(newForum, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
 		if err.Message == domain.NoUser {
 			return ctx.JSON(http.StatusNotFound, err)
 		}
@@ -65,8 +74,17 @@ func (handler *Handler) CreateThread(ctx echo.Context) error {
 
 	newThread.Forum = ctx.Param("slug")
 
-	thread, err := handler.useCase.CreateThread(newThread)
+	thread, err := handler.useCase.CreateThread(newThread, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.UserBanned || err.Message == domain.UserMuted {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
 		if err.Message == domain.NoUser {
 			return ctx.JSON(http.StatusNotFound, err)
 		}
@@ -81,24 +99,123 @@ func (handler *Handler) CreateThread(ctx echo.Context) error {
 
 func (handler *Handler) GetUsersForum(ctx echo.Context) error {
 	slug := ctx.Param("slug")
-	filter := tools.ParseQueryFilterUser(ctx)
+	filter, parseErr := tools.ParseCursorFilterUser(ctx, handler.cursors)
+	if parseErr != nil {
+		return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: domain.BadCursor})
+	}
 
 	users, err := handler.useCase.GetUsersForum(slug, filter)
 	if err != nil {
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
+	if len(users) != 0 {
+		next, prev := tools.PageCursors(filter.Cursor(), len(users), filter.Since != tools.SinceParamDefault,
+			tools.Cursor{Since: users[0].Nickname}, tools.Cursor{Since: users[len(users)-1].Nickname})
+		handler.cursors.SetLinks(ctx, next, prev)
+	}
 	return ctx.JSON(http.StatusOK, users)
 }
 
 func (handler *Handler) GetForumThreads(ctx echo.Context) error {
 	slug := ctx.Param("slug")
-	filter := tools.ParseQueryFilterThread(ctx)
+	filter, parseErr := tools.ParseCursorFilterThread(ctx, handler.cursors)
+	if parseErr != nil {
+		return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: domain.BadCursor})
+	}
 
-	users, err := handler.useCase.GetForumThreads(slug, filter)
+	threads, err := handler.useCase.GetForumThreads(slug, filter)
 	if err != nil {
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
-	return ctx.JSON(http.StatusOK, users)
+	page := threads
+	for len(page) != 0 && page[0].Pinned {
+		page = page[1:]
+	}
+	if len(page) != 0 {
+		first, last := page[0], page[len(page)-1]
+		next, prev := tools.PageCursors(filter.Cursor(), len(page), filter.Since != tools.SinceParamDefault,
+			tools.Cursor{Since: first.Created.Format(time.RFC3339Nano), SinceId: int64(first.Id)},
+			tools.Cursor{Since: last.Created.Format(time.RFC3339Nano), SinceId: int64(last.Id)})
+		handler.cursors.SetLinks(ctx, next, prev)
+	}
+	return ctx.JSON(http.StatusOK, threads)
+}
+
+func (handler *Handler) GetRoles(ctx echo.Context) error {
+	roles, err := handler.useCase.GetRoles(ctx.Param("slug"))
+	if err != nil {
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, roles)
+}
+
+func (handler *Handler) GrantRole(ctx echo.Context) error {
+	var role domain.Role
+
+	if err := ctx.Bind(&role); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctx.Validate(&role); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	role.Forum = ctx.Param("slug")
+	role.Nickname = ctx.Param("nickname")
+
+	result, err := handler.useCase.GrantRole(role, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.BadRole {
+			return ctx.JSON(http.StatusBadRequest, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug || err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (handler *Handler) RevokeRole(ctx echo.Context) error {
+	err := handler.useCase.RevokeRole(ctx.Param("slug"), ctx.Param("nickname"), middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (handler *Handler) GrantSiteAdmin(ctx echo.Context) error {
+	return handler.setSiteAdmin(ctx, true)
+}
+
+func (handler *Handler) RevokeSiteAdmin(ctx echo.Context) error {
+	return handler.setSiteAdmin(ctx, false)
+}
+
+func (handler *Handler) setSiteAdmin(ctx echo.Context, admin bool) error {
+	err := handler.useCase.SetSiteAdmin(ctx.Param("nickname"), admin)
+	if err != nil {
+		if err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
 }
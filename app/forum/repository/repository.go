@@ -49,11 +49,11 @@ func (repository *Repository) GetDetailsForum(slug string) (domain.Forum, error)
 func (repository *Repository) AddThread(thread domain.Thread) (domain.Thread, error) {
 	row := repository.db.QueryRow(`INSERT INTO thread (title, author, forum, message, slug, created)
 		VALUES ($1, $2, COALESCE((SELECT slug from forum where slug = $3), $3), $4, coalesce(nullif($5,'')), $6) 
-		returning id, title, author, forum, message, slug, created`,
+		returning id, title, author, forum, message, slug, created, status`,
 		thread.Title, thread.Author, thread.Forum, thread.Message, thread.Slug, thread.Created)
 
 	var nullSlug sql.NullString
-	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &nullSlug, &thread.Created)
+	err := row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &nullSlug, &thread.Created, &thread.Status)
 	if err != nil {
 		return domain.Thread{}, err
 	}
@@ -124,15 +124,30 @@ func (repository *Repository) GetForumThreads(slug string, filter tools.FilterTh
 	}
 
 	if filter.Since == "" {
-		rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created `+
-			`from thread where forum = $1 order by created `+filter.Sort+` limit $2`, slug, filter.Limit)
+		rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order `+
+			`from thread where forum = $1 and (not archived or $3) and not pinned order by created `+filter.Sort+`, id `+filter.Sort+` limit $2`,
+			slug, filter.Limit, filter.IncludeArchived)
+	} else if filter.SinceId != 0 {
+		if filter.Sort == tools.SortParamTrue {
+			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+				from thread where forum = $1 and (not archived or $5) and not pinned and (created, id) < ($3, $4) 
+				order by created desc, id desc limit $2`,
+				slug, filter.Limit, filter.Since, filter.SinceId, filter.IncludeArchived)
+		} else {
+			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+				from thread where forum = $1 and (not archived or $5) and not pinned and (created, id) > ($3, $4) 
+				order by created asc, id asc limit $2`,
+				slug, filter.Limit, filter.Since, filter.SinceId, filter.IncludeArchived)
+		}
 	} else {
 		if filter.Sort == tools.SortParamTrue {
-			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created 
-				from thread where forum = $1 and created <= $3 order by created `+filter.Sort+` limit $2`, slug, filter.Limit, filter.Since)
+			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+				from thread where forum = $1 and (not archived or $4) and not pinned and created <= $3 
+				order by created `+filter.Sort+`, id `+filter.Sort+` limit $2`, slug, filter.Limit, filter.Since, filter.IncludeArchived)
 		} else {
-			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created 
-				from thread where forum = $1 and created >= $3 order by created `+filter.Sort+` limit $2`, slug, filter.Limit, filter.Since)
+			rows, err = repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+				from thread where forum = $1 and (not archived or $4) and not pinned and created >= $3 
+				order by created `+filter.Sort+`, id `+filter.Sort+` limit $2`, slug, filter.Limit, filter.Since, filter.IncludeArchived)
 		}
 	}
 
@@ -146,7 +161,7 @@ func (repository *Repository) GetForumThreads(slug string, filter tools.FilterTh
 	for rows.Next() {
 		var thread domain.Thread
 		err = rows.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message,
-			&thread.Votes, &nullSlug, &thread.Created)
+			&thread.Votes, &nullSlug, &thread.Created, &thread.Archived, &thread.Status, &thread.Pinned, &thread.PinOrder)
 		if err != nil {
 			return nil, err
 		}
@@ -157,6 +172,31 @@ func (repository *Repository) GetForumThreads(slug string, filter tools.FilterTh
 	return threads, nil
 }
 
+func (repository *Repository) GetPinnedThreads(slug string, includeArchived bool) ([]domain.Thread, error) {
+	rows, err := repository.db.Query(`select id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+		from thread where forum = $1 and pinned and (not archived or $2) 
+		order by pin_order, created desc, id desc`, slug, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nullSlug sql.NullString
+	var threads []domain.Thread
+	for rows.Next() {
+		var thread domain.Thread
+		err = rows.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message,
+			&thread.Votes, &nullSlug, &thread.Created, &thread.Archived, &thread.Status, &thread.Pinned, &thread.PinOrder)
+		if err != nil {
+			return nil, err
+		}
+		thread.Slug = nullSlug.String
+		threads = append(threads, thread)
+	}
+
+	return threads, rows.Err()
+}
+
 func (repository *Repository) GetForumBySlug(slug string) (domain.Forum, error) {
 	var result domain.Forum
 	row := repository.db.QueryRow(`SELECT slug, title, "user", posts, threads
@@ -169,3 +209,88 @@ func (repository *Repository) GetForumBySlug(slug string) (domain.Forum, error)
 
 	return result, nil
 }
+
+func (repository *Repository) GetForumRole(forum string, nickname string) (string, error) {
+	var role string
+	row := repository.db.QueryRow(`SELECT CASE 
+			WHEN EXISTS (SELECT 1 FROM forum_role WHERE forum IS NULL AND nickname = $2) THEN 'admin' 
+			WHEN f."user" = $2 THEN 'owner' 
+			ELSE COALESCE((SELECT role FROM forum_role WHERE forum = f.slug AND nickname = $2), 'member') 
+		END 
+		FROM forum f WHERE f.slug = $1`, forum, nickname)
+
+	err := row.Scan(&role)
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (repository *Repository) GetSiteRole(nickname string) (string, error) {
+	var role string
+	row := repository.db.QueryRow(`SELECT CASE 
+			WHEN EXISTS (SELECT 1 FROM forum_role WHERE forum IS NULL AND nickname = $1) THEN 'admin' 
+			ELSE 'member' 
+		END`, nickname)
+
+	err := row.Scan(&role)
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (repository *Repository) GetForumRoles(forum string) ([]domain.Role, error) {
+	rows, err := repository.db.Query(`SELECT forum, nickname, role, granted 
+		FROM forum_role WHERE forum = $1 ORDER BY role, nickname`, forum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		err = rows.Scan(&role.Forum, &role.Nickname, &role.Role, &role.Granted)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (repository *Repository) SetForumRole(role domain.Role) (domain.Role, error) {
+	row := repository.db.QueryRow(`INSERT INTO forum_role (forum, nickname, role) 
+		VALUES (COALESCE((SELECT slug FROM forum WHERE slug = $1), $1), 
+			COALESCE((SELECT nickname FROM users WHERE nickname = $2), $2), $3) 
+		ON CONFLICT (forum, nickname) WHERE forum IS NOT NULL 
+		DO UPDATE SET role = EXCLUDED.role, granted = NOW() 
+		RETURNING forum, nickname, role, granted`, role.Forum, role.Nickname, role.Role)
+
+	err := row.Scan(&role.Forum, &role.Nickname, &role.Role, &role.Granted)
+	if err != nil {
+		return domain.Role{}, err
+	}
+
+	return role, nil
+}
+
+func (repository *Repository) DeleteForumRole(forum string, nickname string) error {
+	_, err := repository.db.Exec(`DELETE FROM forum_role WHERE forum = $1 AND nickname = $2`, forum, nickname)
+	return err
+}
+
+func (repository *Repository) SetSiteAdmin(nickname string, admin bool) error {
+	var err error
+	if admin {
+		_, err = repository.db.Exec(`INSERT INTO forum_role (forum, nickname, role) VALUES (NULL, $1, 'admin') 
+			ON CONFLICT (nickname) WHERE forum IS NULL DO NOTHING`, nickname)
+	} else {
+		_, err = repository.db.Exec(`DELETE FROM forum_role WHERE forum IS NULL AND nickname = $1`, nickname)
+	}
+	return err
+}
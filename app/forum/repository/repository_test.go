@@ -0,0 +1,67 @@
+package forumrepository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/testdb"
+	"github.com/Kostich31/techpark_db/app/tools"
+)
+
+func TestGetForumThreadsKeysetPagesSharedTimestamps(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.Exec(t, db, `INSERT INTO users (nickname, fullname, email) VALUES ('author', 'Author', 'author@example.com')`)
+	testdb.Exec(t, db, `INSERT INTO forum (title, "user", slug) VALUES ('Forum', 'author', 'forum')`)
+	// Most threads share one timestamp, with a few before and after it so
+	// pages also cross timestamp boundaries.
+	testdb.Exec(t, db, `INSERT INTO thread (title, author, forum, message, created) 
+		SELECT 'Thread ' || n, 'author', 'forum', 'text', 
+			'2020-01-01T00:00:00Z'::timestamptz + CASE WHEN n <= 3 THEN -1 WHEN n > 40 THEN 1 ELSE 0 END * INTERVAL '1 hour' 
+		FROM generate_series(1, 45) AS n`)
+	const total = 45
+
+	repository := NewRepository(db)
+	for _, sort := range []string{tools.SortParamDefault, tools.SortParamTrue} {
+		for _, limit := range []int{1, 4, 7, 45} {
+			filter := tools.FilterThread{Limit: limit, Sort: sort}
+			seen := map[int32]bool{}
+			var previous *domain.Thread
+			for pages := 0; ; pages++ {
+				if pages > total {
+					t.Fatalf("sort %s, limit %d: paging does not terminate", sort, limit)
+				}
+				threads, err := repository.GetForumThreads("forum", filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(threads) == 0 {
+					break
+				}
+				for i := range threads {
+					thread := threads[i]
+					if seen[thread.Id] {
+						t.Fatalf("sort %s, limit %d: thread %d repeated", sort, limit, thread.Id)
+					}
+					seen[thread.Id] = true
+					if previous != nil && !inOrder(*previous, thread, sort == tools.SortParamTrue) {
+						t.Fatalf("sort %s, limit %d: thread %d follows %d out of order", sort, limit, thread.Id, previous.Id)
+					}
+					previous = &thread
+				}
+				filter.Since = previous.Created.Format(time.RFC3339Nano)
+				filter.SinceId = int64(previous.Id)
+			}
+			if len(seen) != total {
+				t.Errorf("sort %s, limit %d: walked %d threads, want %d", sort, limit, len(seen), total)
+			}
+		}
+	}
+}
+
+func inOrder(a domain.Thread, b domain.Thread, desc bool) bool {
+	if desc {
+		a, b = b, a
+	}
+	return a.Created.Before(b.Created) || a.Created.Equal(b.Created) && a.Id < b.Id
+}
@@ -9,13 +9,21 @@ import (
 type UseCase struct {
 	RepositoryForum  domain.ForumRepository
 	RepositoryThread domain.ThreadRepository
+	RepositoryUser   domain.UserRepository
+	RequireAuth      bool
 }
 
-func NewUseCase(repositoryForum domain.ForumRepository, repository domain.ThreadRepository) *UseCase {
-	return &UseCase{RepositoryForum: repositoryForum, RepositoryThread: repository}
+func NewUseCase(repositoryForum domain.ForumRepository, repository domain.ThreadRepository,
+	userRepository domain.UserRepository, requireAuth bool) *UseCase {
+	return &UseCase{RepositoryForum: repositoryForum, RepositoryThread: repository, RepositoryUser: userRepository,
+		RequireAuth: requireAuth}
 }
 
-func (uc *UseCase) CreateForum(forumGet domain.Forum) (domain.Forum, *domain.CustomError) {
+func (uc *UseCase) CreateForum(forumGet domain.Forum, actor domain.Actor) (domain.Forum, *domain.CustomError) {
+	if err := actor.ActFor(forumGet.User, uc.RequireAuth); err != nil {
+		return domain.Forum{}, err
+	}
+
 	forum, err := uc.RepositoryForum.AddForum(forumGet)
 	if err != nil {
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
@@ -42,7 +50,18 @@ func (uc *UseCase) GetDetailsForum(slug string) (domain.Forum, *domain.CustomErr
 	return forum, nil
 }
 
-func (uc *UseCase) CreateThread(threadGet domain.Thread) (domain.Thread, *domain.CustomError) {
+func (uc *UseCase) CreateThread(threadGet domain.Thread, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	if err := uc.actFor(threadGet.Forum, threadGet.Author, actor); err != nil {
+		return domain.Thread{}, err
+	}
+	sanction, err := uc.RepositoryUser.GetActiveSanction(threadGet.Forum, []string{threadGet.Author})
+	if err != nil {
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+	if sanction != nil {
+		return domain.Thread{}, sanction.Error()
+	}
+
 	var randomSlug bool
 	if threadGet.Slug == "" {
 		randomSlug = true
@@ -84,24 +103,170 @@ func (uc *UseCase) GetUsersForum(slug string, filter tools.FilterUser) ([]domain
 		return nil, &domain.CustomError{Message: err.Error()}
 	}
 
+	if filter.Backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
 	return users, nil
 }
 
 func (uc *UseCase) GetForumThreads(slug string, filter tools.FilterThread) ([]domain.Thread, *domain.CustomError) {
-	threads, err := uc.RepositoryForum.GetForumThreads(slug, filter)
+	// A backward page is the first one when nothing precedes it, which one
+	// extra row tells apart from a page that is merely full.
+	query := filter
+	if filter.Backward {
+		query.Limit++
+	}
+	threads, err := uc.RepositoryForum.GetForumThreads(slug, query)
 	if threads == nil {
 		_, err := uc.RepositoryForum.GetForumBySlug(slug)
 		if err != nil {
 			return nil, &domain.CustomError{Message: err.Error()}
 		}
-		return []domain.Thread{}, nil
-	}
-	if err != nil {
+		threads = []domain.Thread{}
+	} else if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, &domain.CustomError{Message: domain.NoSlug}
 		}
 		return nil, &domain.CustomError{Message: err.Error()}
 	}
 
+	first := filter.Since == tools.SinceParamDefault
+	if filter.Backward {
+		first = len(threads) <= filter.Limit
+		if !first {
+			threads = threads[:filter.Limit]
+		}
+		for i, j := 0, len(threads)-1; i < j; i, j = i+1, j-1 {
+			threads[i], threads[j] = threads[j], threads[i]
+		}
+	}
+
+	// Pinned threads head the first page only; later pages are plain keyset
+	// pages that never contain them.
+	if first {
+		pinned, err := uc.RepositoryForum.GetPinnedThreads(slug, filter.IncludeArchived)
+		if err != nil {
+			return nil, &domain.CustomError{Message: err.Error()}
+		}
+		if len(pinned) != 0 {
+			threads = append(pinned, threads...)
+		}
+	}
 	return threads, nil
 }
+
+func (uc *UseCase) GetRoles(forum string) ([]domain.Role, *domain.CustomError) {
+	roles, err := uc.RepositoryForum.GetForumRoles(forum)
+	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+	if roles == nil {
+		_, err = uc.RepositoryForum.GetForumBySlug(forum)
+		if err != nil {
+			return nil, &domain.CustomError{Message: domain.NoSlug}
+		}
+		return []domain.Role{}, nil
+	}
+
+	return roles, nil
+}
+
+func (uc *UseCase) GrantRole(role domain.Role, actor domain.Actor) (domain.Role, *domain.CustomError) {
+	switch role.Role {
+	case domain.RoleModerator, domain.RoleMember, domain.RoleBanned:
+	default:
+		return domain.Role{}, &domain.CustomError{Message: domain.BadRole}
+	}
+	if err := uc.checkRoleChange(role.Forum, role.Nickname, role.Role, actor); err != nil {
+		return domain.Role{}, err
+	}
+
+	result, err := uc.RepositoryForum.SetForumRole(role)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return domain.Role{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Role{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return result, nil
+}
+
+func (uc *UseCase) RevokeRole(forum string, nickname string, actor domain.Actor) *domain.CustomError {
+	if err := uc.checkRoleChange(forum, nickname, "", actor); err != nil {
+		return err
+	}
+
+	if err := uc.RepositoryForum.DeleteForumRole(forum, nickname); err != nil {
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (uc *UseCase) SetSiteAdmin(nickname string, admin bool) *domain.CustomError {
+	err := uc.RepositoryForum.SetSiteAdmin(nickname, admin)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return &domain.CustomError{Message: domain.NoUser}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+// actFor lets the actor write as nickname in forum, where moderators and
+// above may also write for others.
+func (uc *UseCase) actFor(forum string, nickname string, actor domain.Actor) *domain.CustomError {
+	customErr := actor.ActFor(nickname, uc.RequireAuth)
+	if customErr == nil || customErr.Message != domain.Forbidden {
+		return customErr
+	}
+
+	role, err := actor.RoleIn(uc.RepositoryForum, forum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if !domain.RoleAtLeast(role, domain.RoleModerator) {
+		return customErr
+	}
+
+	return nil
+}
+
+// checkRoleChange lets moderators manage members and bans while appointing
+// or removing moderators takes the owner. Owners and admins are derived and
+// cannot be reassigned here.
+func (uc *UseCase) checkRoleChange(forum string, nickname string, role string, actor domain.Actor) *domain.CustomError {
+	actorRole, err := actor.RoleIn(uc.RepositoryForum, forum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	current, err := uc.RepositoryForum.GetForumRole(forum, nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	needed := domain.RoleModerator
+	if role == domain.RoleModerator || current == domain.RoleModerator {
+		needed = domain.RoleOwner
+	}
+	if !domain.RoleAtLeast(actorRole, needed) || domain.RoleAtLeast(current, domain.RoleOwner) {
+		return &domain.CustomError{Message: domain.Forbidden}
+	}
+
+	return nil
+}
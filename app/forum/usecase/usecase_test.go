@@ -0,0 +1,73 @@
+package forumusecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/tools"
+)
+
+// fakeForums holds the regular threads of one forum, oldest first, and
+// answers backward pages the way the repository does: nearest thread first.
+type fakeForums struct {
+	domain.ForumRepository
+	threads []domain.Thread
+	pinned  []domain.Thread
+}
+
+func (repository *fakeForums) GetForumThreads(slug string, filter tools.FilterThread) ([]domain.Thread, error) {
+	var result []domain.Thread
+	for i := len(repository.threads) - 1; i >= 0 && len(result) < filter.Limit; i-- {
+		if repository.threads[i].Id < int32(filter.SinceId) {
+			result = append(result, repository.threads[i])
+		}
+	}
+	return result, nil
+}
+
+func (repository *fakeForums) GetForumBySlug(slug string) (domain.Forum, error) {
+	return domain.Forum{Slug: slug}, nil
+}
+
+func (repository *fakeForums) GetPinnedThreads(slug string, includeArchived bool) ([]domain.Thread, error) {
+	return repository.pinned, nil
+}
+
+func TestPrevCursorBackToFirstPageKeepsPins(t *testing.T) {
+	forums := &fakeForums{pinned: []domain.Thread{{Id: 100, Pinned: true}}}
+	for id := int32(1); id <= 6; id++ {
+		forums.threads = append(forums.threads, domain.Thread{Id: id, Created: time.Unix(int64(id), 0)})
+	}
+	uc := NewUseCase(forums, nil, nil, false)
+
+	cases := []struct {
+		sinceId int64
+		want    []int32
+	}{
+		{3, []int32{100, 1, 2}},
+		{4, []int32{2, 3}},
+		{1, []int32{100}},
+	}
+	for _, c := range cases {
+		filter := tools.FilterThread{Limit: 2, Sort: tools.SortParamTrue, Since: "cursor", SinceId: c.sinceId, Backward: true}
+		threads, err := uc.GetForumThreads("forum", filter)
+		if err != nil {
+			t.Fatal(err.Message)
+		}
+		var got []int32
+		for _, thread := range threads {
+			got = append(got, thread.Id)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("before %d: got %v, want %v", c.sinceId, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("before %d: got %v, want %v", c.sinceId, got, c.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderAdminToken = "X-Admin-Token"
+	adminKey         = "admin"
+)
+
+// AdminOnly guards operator endpoints with a shared token. An empty token
+// disables them entirely.
+func AdminOnly(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !hasAdminToken(ctx, token) {
+				return ctx.JSON(http.StatusForbidden, domain.CustomError{Message: domain.Forbidden})
+			}
+			return next(ctx)
+		}
+	}
+}
+
+func IsAdmin(ctx echo.Context) bool {
+	admin, _ := ctx.Get(adminKey).(bool)
+	return admin
+}
+
+func hasAdminToken(ctx echo.Context, token string) bool {
+	given := ctx.Request().Header.Get(HeaderAdminToken)
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	bearerPrefix = "Bearer "
+	userKey      = "user"
+)
+
+// Authenticate resolves the caller from an "Authorization: Bearer" token and
+// the admin token. Requests without credentials pass through anonymously; a
+// token that does not resolve is rejected.
+func Authenticate(auth domain.AuthUseCase, adminToken string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(adminKey, hasAdminToken(ctx, adminToken))
+
+			header := ctx.Request().Header.Get(echo.HeaderAuthorization)
+			if header == "" {
+				return next(ctx)
+			}
+			if !strings.HasPrefix(header, bearerPrefix) {
+				return ctx.JSON(http.StatusUnauthorized, domain.CustomError{Message: domain.Unauthorized})
+			}
+
+			nickname, err := auth.Authenticate(strings.TrimPrefix(header, bearerPrefix))
+			if err != nil {
+				if err.Message == domain.Unauthorized {
+					return ctx.JSON(http.StatusUnauthorized, err)
+				}
+				return ctx.JSON(http.StatusInternalServerError, err)
+			}
+			ctx.Set(userKey, nickname)
+
+			return next(ctx)
+		}
+	}
+}
+
+// RequireUser rejects anonymous callers when required is set.
+func RequireUser(required bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if required && CurrentActor(ctx).Nickname == "" && !IsAdmin(ctx) {
+				return ctx.JSON(http.StatusUnauthorized, domain.CustomError{Message: domain.Unauthorized})
+			}
+			return next(ctx)
+		}
+	}
+}
+
+func CurrentActor(ctx echo.Context) domain.Actor {
+	nickname, _ := ctx.Get(userKey).(string)
+	return domain.Actor{Nickname: nickname, Admin: IsAdmin(ctx)}
+}
+
+// BearerToken returns the raw token the caller authenticated with.
+func BearerToken(ctx echo.Context) string {
+	return strings.TrimPrefix(ctx.Request().Header.Get(echo.HeaderAuthorization), bearerPrefix)
+}
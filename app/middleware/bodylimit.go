@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// BodyLimit answers 413 when the request body is longer than maxBytes. The
+// body is buffered so handlers still bind it as usual; zero disables the
+// check.
+func BodyLimit(maxBytes int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if maxBytes <= 0 {
+			return next
+		}
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			if request.ContentLength > maxBytes {
+				return ctx.JSON(http.StatusRequestEntityTooLarge, domain.CustomError{Message: domain.RequestTooLarge})
+			}
+			if request.Body == nil || request.Body == http.NoBody {
+				return next(ctx)
+			}
+
+			body, err := ioutil.ReadAll(io.LimitReader(request.Body, maxBytes+1))
+			request.Body.Close()
+			if err != nil {
+				return ctx.JSON(http.StatusBadRequest, err.Error())
+			}
+			if int64(len(body)) > maxBytes {
+				return ctx.JSON(http.StatusRequestEntityTooLarge, domain.CustomError{Message: domain.RequestTooLarge})
+			}
+			request.Body = ioutil.NopCloser(bytes.NewReader(body))
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderRetryAfter = "Retry-After"
+
+type KeyFunc func(ctx echo.Context) string
+
+// ByCaller keys buckets by the authenticated user, falling back to the
+// client address for anonymous requests.
+func ByCaller(ctx echo.Context) string {
+	if nickname := CurrentActor(ctx).Nickname; nickname != "" {
+		return "user:" + nickname
+	}
+	return "ip:" + ctx.RealIP()
+}
+
+// ByParam keys buckets by a path parameter, e.g. the forum slug.
+func ByParam(name string) KeyFunc {
+	return func(ctx echo.Context) string {
+		return name + ":" + ctx.Param(name)
+	}
+}
+
+// CostFunc reports how many tokens a request spends.
+type CostFunc func(ctx echo.Context) (int, error)
+
+// PerRequest charges one token for every request.
+func PerRequest(ctx echo.Context) (int, error) {
+	return 1, nil
+}
+
+// PerItem charges one token for every element of a JSON array body, so a
+// batch costs as much as sending its items one by one. The body is put back
+// for the handler to bind; a body that is not an array costs one token and
+// is left for the handler to reject.
+func PerItem(ctx echo.Context) (int, error) {
+	request := ctx.Request()
+	if request.Body == nil || request.Body == http.NoBody {
+		return 1, nil
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return 0, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil || len(items) == 0 {
+		return 1, nil
+	}
+	return len(items), nil
+}
+
+// RateLimit answers 429 with Retry-After once the bucket for the request's
+// key is empty. A disabled limit is a no-op, and store failures let the
+// request through.
+func RateLimit(store ratelimit.Store, scope string, limit ratelimit.Limit, key KeyFunc, cost CostFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !limit.Enabled() {
+			return next
+		}
+		return func(ctx echo.Context) error {
+			tokens, err := cost(ctx)
+			if err != nil {
+				return ctx.JSON(http.StatusBadRequest, err.Error())
+			}
+			ok, wait, err := store.Take(scope+"|"+key(ctx), limit, tokens)
+			if err != nil {
+				ctx.Logger().Error(err)
+				return next(ctx)
+			}
+			if !ok {
+				seconds := int(math.Ceil(wait.Seconds()))
+				ctx.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(seconds))
+				return ctx.JSON(http.StatusTooManyRequests, domain.CustomError{Message: domain.TooManyRequests})
+			}
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kostich31/techpark_db/app/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+func TestPostBatchSpendsATokenPerPost(t *testing.T) {
+	router := echo.New()
+	limit := ratelimit.Limit{Rate: 5 / time.Minute.Seconds(), Burst: 5}
+	limited := RateLimit(ratelimit.NewMemoryStore(), "posts", limit, ByCaller, PerItem)
+	router.POST("/posts", func(ctx echo.Context) error {
+		var posts []struct{ Message string }
+		if err := ctx.Bind(&posts); err != nil {
+			return err
+		}
+		return ctx.JSON(http.StatusCreated, len(posts))
+	}, limited)
+
+	batches := []struct {
+		body string
+		want int
+	}{
+		{`[{"message":"a"},{"message":"b"},{"message":"c"}]`, http.StatusCreated},
+		{`[{"message":"d"},{"message":"e"},{"message":"f"}]`, http.StatusTooManyRequests},
+		{`[{"message":"g"},{"message":"h"}]`, http.StatusCreated},
+	}
+	for i, batch := range batches {
+		request := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(batch.body))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != batch.want {
+			t.Errorf("batch %d: got %d, want %d", i, recorder.Code, batch.want)
+		}
+		posts := strconv.Itoa(strings.Count(batch.body, "message"))
+		if batch.want == http.StatusCreated && strings.TrimSpace(recorder.Body.String()) != posts {
+			t.Errorf("batch %d: handler saw %s", i, recorder.Body.String())
+		}
+		if batch.want == http.StatusTooManyRequests && recorder.Header().Get(HeaderRetryAfter) == "" {
+			t.Errorf("batch %d: no %s header", i, HeaderRetryAfter)
+		}
+	}
+}
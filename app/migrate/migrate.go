@@ -0,0 +1,409 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+const LockKey = 7245001
+
+var (
+	fileName     = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+	modeFileName = regexp.MustCompile(`^(.+)\.(enable|disable)\.sql$`)
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Mode is an optional, reversible schema change that sits outside the
+// numbered sequence, such as switching tables to UNLOGGED for load tests.
+type Mode struct {
+	Name    string
+	Enable  string
+	Disable string
+}
+
+type ModeStatus struct {
+	Name      string
+	Enabled   bool
+	EnabledAt time.Time
+}
+
+type Migrator struct {
+	db         *pgx.ConnPool
+	migrations []Migration
+	modes      []Mode
+}
+
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	var result []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d has no up file", migration.Version)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+func LoadModes(fsys fs.FS, dir string) ([]Mode, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*Mode{}
+	for _, entry := range entries {
+		match := modeFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		body, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mode, ok := byName[match[1]]
+		if !ok {
+			mode = &Mode{Name: match[1]}
+			byName[match[1]] = mode
+		}
+		if match[2] == "enable" {
+			mode.Enable = string(body)
+		} else {
+			mode.Disable = string(body)
+		}
+	}
+
+	var result []Mode
+	for _, mode := range byName {
+		if mode.Enable == "" || mode.Disable == "" {
+			return nil, fmt.Errorf("migrate: mode %s needs both enable and disable files", mode.Name)
+		}
+		result = append(result, *mode)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+func NewMigrator(db *pgx.ConnPool, migrations []Migration, modes []Mode) *Migrator {
+	return &Migrator{db: db, migrations: migrations, modes: modes}
+}
+
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+func (m *Migrator) Down() error {
+	return m.withLock(func(conn *pgx.Conn, applied map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.down(conn, m.migrations[i])
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) To(version int) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("migrate: unknown version %d", version)
+	}
+
+	return m.withLock(func(conn *pgx.Conn, applied map[int]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.down(conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.up(conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	var result []Status
+	err := m.withLock(func(conn *pgx.Conn, applied map[int]time.Time) error {
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+			result = append(result, Status{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) Enable(name string) error {
+	return m.toggleMode(name, true)
+}
+
+func (m *Migrator) Disable(name string) error {
+	return m.toggleMode(name, false)
+}
+
+func (m *Migrator) Modes() ([]ModeStatus, error) {
+	var result []ModeStatus
+	err := m.withLock(func(conn *pgx.Conn, applied map[int]time.Time) error {
+		enabled, err := enabledModes(conn)
+		if err != nil {
+			return err
+		}
+		for _, mode := range m.modes {
+			enabledAt, ok := enabled[mode.Name]
+			result = append(result, ModeStatus{Name: mode.Name, Enabled: ok, EnabledAt: enabledAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) toggleMode(name string, enable bool) error {
+	var mode *Mode
+	for i := range m.modes {
+		if m.modes[i].Name == name {
+			mode = &m.modes[i]
+		}
+	}
+	if mode == nil {
+		return fmt.Errorf("migrate: unknown mode %s", name)
+	}
+
+	return m.withLock(func(conn *pgx.Conn, applied map[int]time.Time) error {
+		enabled, err := enabledModes(conn)
+		if err != nil {
+			return err
+		}
+		if _, ok := enabled[name]; ok == enable {
+			return nil
+		}
+
+		tx, err := conn.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if enable {
+			_, err = tx.Exec(mode.Enable)
+			if err == nil {
+				_, err = tx.Exec(`INSERT INTO schema_modes (name) VALUES ($1)`, name)
+			}
+		} else {
+			_, err = tx.Exec(mode.Disable)
+			if err == nil {
+				_, err = tx.Exec(`DELETE FROM schema_modes WHERE name = $1`, name)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("migrate: mode %s: %w", name, err)
+		}
+
+		return tx.Commit()
+	})
+}
+
+func (m *Migrator) find(version int) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) withLock(fn func(conn *pgx.Conn, applied map[int]time.Time) error) error {
+	conn, err := m.db.Acquire()
+	if err != nil {
+		return err
+	}
+	defer m.db.Release(conn)
+
+	if _, err := conn.Exec(`SELECT pg_advisory_lock($1)`, LockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(`SELECT pg_advisory_unlock($1)`, LockKey)
+
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(`CREATE TABLE IF NOT EXISTS schema_modes (
+		name TEXT PRIMARY KEY,
+		enabled_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+func appliedVersions(conn *pgx.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return applied, nil
+}
+
+func enabledModes(conn *pgx.Conn) (map[string]time.Time, error) {
+	rows, err := conn.Query(`SELECT name, enabled_at FROM schema_modes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enabled := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var enabledAt time.Time
+		if err := rows.Scan(&name, &enabledAt); err != nil {
+			return nil, err
+		}
+		enabled[name] = enabledAt
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return enabled, nil
+}
+
+func (m *Migrator) up(conn *pgx.Conn, migration Migration) error {
+	return m.apply(conn, migration, migration.Up,
+		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+}
+
+func (m *Migrator) down(conn *pgx.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migrate: version %d cannot be rolled back", migration.Version)
+	}
+	return m.apply(conn, migration, migration.Down,
+		`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+}
+
+func (m *Migrator) apply(conn *pgx.Conn, migration Migration, body string, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(body); err != nil {
+		return fmt.Errorf("migrate: %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second and holding at
+// most Burst tokens. The zero Limit lets everything through.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (limit Limit) Enabled() bool {
+	return limit.Rate > 0 && limit.Burst > 0
+}
+
+// ParseLimit reads "N/duration", e.g. "60/1m" for sixty requests a minute
+// with bursts of up to sixty. An empty string disables the limit.
+func ParseLimit(raw string) (Limit, error) {
+	if raw == "" {
+		return Limit{}, nil
+	}
+	parts := strings.SplitN(raw, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("ratelimit: %q is not N/duration", raw)
+	}
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: %q needs a positive count", raw)
+	}
+	per, err := time.ParseDuration(parts[1])
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: %q needs a positive duration", raw)
+	}
+	return Limit{Rate: float64(count) / per.Seconds(), Burst: count}, nil
+}
+
+// Store keeps buckets. MemoryStore is per process; a shared implementation
+// lets several instances enforce one budget.
+type Store interface {
+	// Take spends cost tokens from the bucket at key, or reports how long
+	// until that many are available.
+	Take(key string, limit Limit, cost int) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (store *MemoryStore) Take(key string, limit Limit, cost int) (bool, time.Duration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	if now.Sub(store.lastSweep) > sweepInterval {
+		store.sweep(now)
+	}
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		store.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	// A cost above the burst can never be paid; the caller waits for a full
+	// bucket and still has to split the request.
+	need := math.Min(float64(cost), float64(limit.Burst))
+	if cost <= limit.Burst && b.tokens >= need {
+		b.tokens -= need
+		b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second)))
+		return true, 0, nil
+	}
+	wait := time.Duration(math.Max(need-b.tokens, 0) / limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops buckets that have refilled, which is the same as never having
+// seen the key.
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, b := range store.buckets {
+		if now.After(b.full) {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
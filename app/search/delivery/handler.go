@@ -0,0 +1,52 @@
+package searchdelivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/tools"
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	UseCase domain.SearchUseCase
+	Cursors *tools.CursorSigner
+}
+
+func NewHandler(useCase domain.SearchUseCase, cursors *tools.CursorSigner) *Handler {
+	return &Handler{UseCase: useCase, Cursors: cursors}
+}
+
+func (handler *Handler) Search(ctx echo.Context) error {
+	filter, parseErr := tools.ParseCursorFilterSearch(ctx, handler.Cursors)
+	if parseErr != nil {
+		return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: domain.BadCursor})
+	}
+
+	results, err := handler.UseCase.Search(filter)
+	if err != nil {
+		if err.Message == domain.EmptySearchQuery || err.Message == domain.BadSearchType ||
+			err.Message == domain.BadSearchLimit {
+			return ctx.JSON(http.StatusBadRequest, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	if len(results) != 0 {
+		next, _ := tools.PageCursors(filter.Cursor(), len(results), false, tools.Cursor{},
+			searchCursor(results[len(results)-1]))
+		handler.Cursors.SetLinks(ctx, next, nil)
+	}
+	return ctx.JSON(http.StatusOK, results)
+}
+
+func searchCursor(result domain.SearchResult) tools.Cursor {
+	cursor := tools.Cursor{Since: strconv.FormatFloat(float64(result.Rank), 'g', -1, 32)}
+	if result.Post != nil {
+		cursor.SinceId = result.Post.Id
+	} else {
+		cursor.SinceId = int64(result.Thread.Id)
+	}
+	return cursor
+}
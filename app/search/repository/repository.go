@@ -0,0 +1,159 @@
+package searchrepository
+
+import (
+	"database/sql"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/tools"
+	"github.com/jackc/pgx"
+)
+
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+type Repository struct {
+	db *pgx.ConnPool
+}
+
+func NewRepository(db *pgx.ConnPool) *Repository {
+	return &Repository{db: db}
+}
+
+func (repository *Repository) SearchPosts(filter tools.FilterSearch) ([]domain.SearchResult, error) {
+	var rows *pgx.Rows
+	var err error
+	if filter.Since == tools.SinceParamDefault {
+		rows, err = repository.db.Query(`
+			SELECT id, parent, author, message, isEdited, forum, thread, created, rank,
+				ts_headline('simple', message, query, $5)
+			FROM (SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created,
+					ts_rank(p.search, query) AS rank, query
+				FROM post p, websearch_to_tsquery('simple', $1) query
+				WHERE p.search @@ query
+				AND ($2 = '' OR p.forum = $2::citext)
+				AND ($3 = '' OR p.author = $3::citext)
+				ORDER BY rank DESC, p.id DESC
+				LIMIT $4) ranked
+			ORDER BY rank DESC, id DESC`,
+			filter.Query, filter.Forum, filter.Author, filter.Limit, headlineOptions)
+	} else {
+		rows, err = repository.db.Query(`
+			SELECT id, parent, author, message, isEdited, forum, thread, created, rank,
+				ts_headline('simple', message, query, $5)
+			FROM (SELECT * FROM (SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created,
+					ts_rank(p.search, query) AS rank, query
+				FROM post p, websearch_to_tsquery('simple', $1) query
+				WHERE p.search @@ query
+				AND ($2 = '' OR p.forum = $2::citext)
+				AND ($3 = '' OR p.author = $3::citext)) matched
+				WHERE (rank, id) < ($6::real, $7)
+				ORDER BY rank DESC, id DESC
+				LIMIT $4) ranked
+			ORDER BY rank DESC, id DESC`,
+			filter.Query, filter.Forum, filter.Author, filter.Limit, headlineOptions, filter.Since, filter.SinceId)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []domain.SearchResult{}
+	for rows.Next() {
+		post := &domain.Post{}
+		item := domain.SearchResult{Type: tools.SearchTypePost, Post: post}
+
+		err = rows.Scan(
+			&post.Id,
+			&post.Parent,
+			&post.Author,
+			&post.Message,
+			&post.IsEdited,
+			&post.Forum,
+			&post.Thread,
+			&post.Created,
+			&item.Rank,
+			&item.Snippet)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) SearchThreads(filter tools.FilterSearch) ([]domain.SearchResult, error) {
+	var rows *pgx.Rows
+	var err error
+	if filter.Since == tools.SinceParamDefault {
+		rows, err = repository.db.Query(`
+			SELECT id, title, author, forum, message, votes, slug, created, rank,
+				ts_headline('simple', message, query, $5)
+			FROM (SELECT t.id, t.title, t.author, t.forum, t.message, t.votes, t.slug, t.created,
+					ts_rank(t.search, query) AS rank, query
+				FROM thread t, websearch_to_tsquery('simple', $1) query
+				WHERE t.search @@ query
+				AND ($2 = '' OR t.forum = $2::citext)
+				AND ($3 = '' OR t.author = $3::citext)
+				ORDER BY rank DESC, t.id DESC
+				LIMIT $4) ranked
+			ORDER BY rank DESC, id DESC`,
+			filter.Query, filter.Forum, filter.Author, filter.Limit, headlineOptions)
+	} else {
+		rows, err = repository.db.Query(`
+			SELECT id, title, author, forum, message, votes, slug, created, rank,
+				ts_headline('simple', message, query, $5)
+			FROM (SELECT * FROM (SELECT t.id, t.title, t.author, t.forum, t.message, t.votes, t.slug, t.created,
+					ts_rank(t.search, query) AS rank, query
+				FROM thread t, websearch_to_tsquery('simple', $1) query
+				WHERE t.search @@ query
+				AND ($2 = '' OR t.forum = $2::citext)
+				AND ($3 = '' OR t.author = $3::citext)) matched
+				WHERE (rank, id) < ($6::real, $7)
+				ORDER BY rank DESC, id DESC
+				LIMIT $4) ranked
+			ORDER BY rank DESC, id DESC`,
+			filter.Query, filter.Forum, filter.Author, filter.Limit, headlineOptions, filter.Since, filter.SinceId)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []domain.SearchResult{}
+	for rows.Next() {
+		thread := &domain.Thread{}
+		item := domain.SearchResult{Type: tools.SearchTypeThread, Thread: thread}
+
+		var nullSlug sql.NullString
+		err = rows.Scan(
+			&thread.Id,
+			&thread.Title,
+			&thread.Author,
+			&thread.Forum,
+			&thread.Message,
+			&thread.Votes,
+			&nullSlug,
+			&thread.Created,
+			&item.Rank,
+			&item.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		thread.Slug = nullSlug.String
+
+		result = append(result, item)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return result, nil
+}
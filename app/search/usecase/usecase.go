@@ -0,0 +1,39 @@
+package searchusecase
+
+import (
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/tools"
+)
+
+type UseCase struct {
+	Repository domain.SearchRepository
+}
+
+func NewUseCase(repository domain.SearchRepository) *UseCase {
+	return &UseCase{Repository: repository}
+}
+
+func (uc *UseCase) Search(filter tools.FilterSearch) ([]domain.SearchResult, *domain.CustomError) {
+	if filter.Query == "" {
+		return nil, &domain.CustomError{Message: domain.EmptySearchQuery}
+	}
+	if filter.Limit <= 0 {
+		return nil, &domain.CustomError{Message: domain.BadSearchLimit}
+	}
+
+	var result []domain.SearchResult
+	var err error
+	switch filter.Type {
+	case tools.SearchTypePost:
+		result, err = uc.Repository.SearchPosts(filter)
+	case tools.SearchTypeThread:
+		result, err = uc.Repository.SearchThreads(filter)
+	default:
+		return nil, &domain.CustomError{Message: domain.BadSearchType}
+	}
+	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+
+	return result, nil
+}
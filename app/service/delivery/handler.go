@@ -2,8 +2,10 @@ package servicedelivery
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/tools"
 	"github.com/labstack/echo/v4"
 )
 
@@ -16,8 +18,13 @@ func NewHandler(useCase domain.ServiceUseCase) *Handler {
 }
 
 func (handler *Handler) Status(ctx echo.Context) error {
-	status, err := handler.UseCase.GetStatus()
+	mode := tools.ParseQueryStatusMode(ctx)
+
+	status, err := handler.UseCase.GetStatus(mode)
 	if err != nil {
+		if err.Error() == domain.BadStatusMode {
+			return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, err)
 	}
 
@@ -32,3 +39,45 @@ func (handler *Handler) Clear(ctx echo.Context) error {
 
 	return ctx.NoContent(http.StatusOK)
 }
+
+func (handler *Handler) Check(ctx echo.Context) error {
+	return handler.check(ctx, false)
+}
+
+func (handler *Handler) Repair(ctx echo.Context) error {
+	return handler.check(ctx, true)
+}
+
+func (handler *Handler) check(ctx echo.Context, repair bool) error {
+	// A missing or malformed batch falls back to the default size.
+	batch, _ := strconv.Atoi(ctx.QueryParam("batch"))
+
+	report, err := handler.UseCase.Check(repair, batch)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, domain.CustomError{Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+func (handler *Handler) Healthz(ctx echo.Context) error {
+	return ctx.String(http.StatusOK, domain.HealthOk)
+}
+
+func (handler *Handler) Readyz(ctx echo.Context) error {
+	err := handler.UseCase.Ready()
+	if err != nil {
+		return ctx.JSON(http.StatusServiceUnavailable, err)
+	}
+
+	return ctx.String(http.StatusOK, domain.HealthOk)
+}
+
+func (handler *Handler) Health(ctx echo.Context) error {
+	health := handler.UseCase.GetHealth()
+	if health.Status != domain.HealthOk {
+		return ctx.JSON(http.StatusServiceUnavailable, health)
+	}
+
+	return ctx.JSON(http.StatusOK, health)
+}
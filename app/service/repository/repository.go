@@ -1,6 +1,9 @@
 package servicerepository
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/Kostich31/techpark_db/app/domain"
 	"github.com/jackc/pgx"
 )
@@ -33,11 +36,256 @@ func (repository *Repository) GetStatus() (domain.Status, error) {
 
 	return result, nil
 }
+
+func (repository *Repository) GetStatusEstimate() (domain.Status, error) {
+	var result domain.Status
+	row := repository.db.QueryRow(
+		`SELECT
+		GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'users'::regclass), 0)::BIGINT,
+		GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'forum'::regclass), 0)::BIGINT,
+		GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'thread'::regclass), 0)::BIGINT,
+		GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'post'::regclass), 0)::BIGINT;`)
+
+	err := row.Scan(
+		&result.User,
+		&result.Forum,
+		&result.Thread,
+		&result.Post)
+	if err != nil {
+		return domain.Status{}, err
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetStatusCached() (domain.Status, error) {
+	var result domain.Status
+	row := repository.db.QueryRow(
+		`SELECT
+		COALESCE((SELECT SUM(total) FROM stats WHERE name = 'users')::BIGINT,
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'users'::regclass), 0)::BIGINT),
+		COALESCE((SELECT SUM(total) FROM stats WHERE name = 'forum')::BIGINT,
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'forum'::regclass), 0)::BIGINT),
+		COALESCE((SELECT SUM(total) FROM stats WHERE name = 'thread')::BIGINT,
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'thread'::regclass), 0)::BIGINT),
+		COALESCE((SELECT SUM(total) FROM stats WHERE name = 'post')::BIGINT,
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'post'::regclass), 0)::BIGINT);`)
+
+	err := row.Scan(
+		&result.User,
+		&result.Forum,
+		&result.Thread,
+		&result.Post)
+	if err != nil {
+		return domain.Status{}, err
+	}
+
+	return result, nil
+}
+
 func (repository *Repository) Clear() error {
-	_, err := repository.db.Exec(`TRUNCATE users, forum, thread, post, vote, users_forum;`)
+	_, err := repository.db.Exec(`TRUNCATE users, forum, thread, post, vote, users_forum, user_password, auth_token, forum_role, sanction, post_vote, reaction, post_revision;`)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (repository *Repository) GetSchemaVersion(ctx context.Context) (int, error) {
+	// The pool waits for a free connection without a deadline, so acquire
+	// under ctx to keep readiness probes bounded when the pool is exhausted.
+	conn, err := repository.db.AcquireEx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer repository.db.Release(conn)
+
+	var version int
+	err = conn.QueryRowEx(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`, nil).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func (repository *Repository) GetPoolStat() domain.PoolStat {
+	stat := repository.db.Stat()
+	return domain.PoolStat{
+		MaxConnections:       stat.MaxConnections,
+		CurrentConnections:   stat.CurrentConnections,
+		AvailableConnections: stat.AvailableConnections,
+	}
+}
+
+var discrepancyQueries = []struct {
+	check string
+	query string
+}{
+	{domain.CheckForumPosts, `SELECT f.slug::text, 0, 0::bigint, '', COALESCE(f.posts, 0), COALESCE(p.total, 0) 
+		FROM forum f LEFT JOIN (SELECT forum, COUNT(*) AS total FROM post WHERE NOT isDeleted GROUP BY forum) p 
+		ON p.forum = f.slug 
+		WHERE COALESCE(f.posts, 0) <> COALESCE(p.total, 0) ORDER BY f.slug`},
+	{domain.CheckForumThreads, `SELECT f.slug::text, 0, 0::bigint, '', COALESCE(f.threads, 0)::bigint, COALESCE(t.total, 0) 
+		FROM forum f LEFT JOIN (SELECT forum, COUNT(*) AS total FROM thread GROUP BY forum) t 
+		ON t.forum = f.slug 
+		WHERE COALESCE(f.threads, 0) <> COALESCE(t.total, 0) ORDER BY f.slug`},
+	{domain.CheckVoteVoice, `SELECT t.forum::text, v.thread, 0::bigint, v.nickname::text, COALESCE(v.voice, 0)::bigint, 
+			COALESCE(sign(v.voice), 0)::bigint 
+		FROM vote v JOIN thread t ON t.id = v.thread 
+		WHERE v.voice IS NULL OR v.voice NOT IN (-1, 1) ORDER BY v.thread, v.nickname`},
+	{domain.CheckThreadVotes, `SELECT t.forum::text, t.id, 0::bigint, '', COALESCE(t.votes, 0)::bigint, COALESCE(v.total, 0) 
+		FROM thread t LEFT JOIN (SELECT thread, SUM(voice) AS total FROM vote GROUP BY thread) v 
+		ON v.thread = t.id 
+		WHERE COALESCE(t.votes, 0) <> COALESCE(v.total, 0) ORDER BY t.id`},
+	{domain.CheckPostScore, `SELECT p.forum::text, p.thread, p.id, '', p.score::bigint, COALESCE(v.total, 0) 
+		FROM post p LEFT JOIN (SELECT post, SUM(voice) AS total FROM post_vote GROUP BY post) v 
+		ON v.post = p.id 
+		WHERE p.score <> COALESCE(v.total, 0) ORDER BY p.id`},
+	{domain.CheckUsersForum, `SELECT s.forum::text, 0, 0::bigint, s.author::text, 0::bigint, 1::bigint 
+		FROM (SELECT forum, author FROM thread UNION SELECT forum, author FROM post) s 
+		WHERE NOT EXISTS (SELECT 1 FROM users_forum uf WHERE uf.slug = s.forum AND uf.nickname = s.author) 
+		UNION ALL 
+		SELECT uf.slug::text, 0, 0::bigint, uf.nickname::text, 1::bigint, 0::bigint 
+		FROM users_forum uf 
+		WHERE NOT EXISTS (SELECT 1 FROM thread t WHERE t.forum = uf.slug AND t.author = uf.nickname) 
+		AND NOT EXISTS (SELECT 1 FROM post p WHERE p.forum = uf.slug AND p.author = uf.nickname)`},
+}
+
+// FindDiscrepancies recomputes every trigger-maintained aggregate from its
+// source table. It only reads, so it takes no locks that block writers.
+func (repository *Repository) FindDiscrepancies() ([]domain.Discrepancy, error) {
+	var result []domain.Discrepancy
+	for _, item := range discrepancyQueries {
+		rows, err := repository.db.Query(item.query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			discrepancy := domain.Discrepancy{Check: item.check}
+			err = rows.Scan(&discrepancy.Forum, &discrepancy.Thread, &discrepancy.Post, &discrepancy.Nickname,
+				&discrepancy.Stored, &discrepancy.Actual)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result = append(result, discrepancy)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+	}
+
+	return result, nil
+}
+
+// Repair recomputes the aggregates behind one batch of discrepancies of the
+// same check. Values are recounted rather than copied from the report, so
+// writes that landed since the check are not undone, and only the rows of the
+// batch are locked.
+func (repository *Repository) Repair(check string, items []domain.Discrepancy) (int64, error) {
+	var forums, nicknames []string
+	var threads []int32
+	var posts []int64
+	for _, item := range items {
+		forums = append(forums, item.Forum)
+		nicknames = append(nicknames, item.Nickname)
+		threads = append(threads, item.Thread)
+		posts = append(posts, item.Post)
+	}
+
+	var tag pgx.CommandTag
+	var err error
+	switch check {
+	case domain.CheckForumPosts:
+		tag, err = repository.db.Exec(`UPDATE forum f 
+			SET posts = (SELECT COUNT(*) FROM post WHERE forum = f.slug AND NOT isDeleted) 
+			WHERE f.slug = ANY($1::text[]::citext[])`, forums)
+	case domain.CheckForumThreads:
+		tag, err = repository.db.Exec(`UPDATE forum f 
+			SET threads = (SELECT COUNT(*) FROM thread WHERE forum = f.slug) 
+			WHERE f.slug = ANY($1::text[]::citext[])`, forums)
+	case domain.CheckThreadVotes:
+		tag, err = repository.db.Exec(`UPDATE thread t 
+			SET votes = COALESCE((SELECT SUM(voice) FROM vote WHERE thread = t.id), 0) 
+			WHERE t.id = ANY($1::int[])`, threads)
+	case domain.CheckVoteVoice:
+		return repository.repairVoteVoice(threads, nicknames)
+	case domain.CheckPostScore:
+		tag, err = repository.db.Exec(`UPDATE post p 
+			SET score = COALESCE((SELECT SUM(voice) FROM post_vote WHERE post = p.id), 0) 
+			WHERE p.id = ANY($1::bigint[])`, posts)
+	case domain.CheckUsersForum:
+		return repository.repairUsersForum(forums, nicknames)
+	default:
+		return 0, fmt.Errorf("check: unknown aggregate %q", check)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (repository *Repository) repairUsersForum(forums []string, nicknames []string) (int64, error) {
+	added, err := repository.db.Exec(`INSERT INTO users_forum (nickname, slug) 
+		SELECT s.nickname::citext, s.forum::citext FROM unnest($1::text[], $2::text[]) AS s(forum, nickname) 
+		WHERE EXISTS (SELECT 1 FROM thread WHERE forum = s.forum::citext AND author = s.nickname::citext) 
+		OR EXISTS (SELECT 1 FROM post WHERE forum = s.forum::citext AND author = s.nickname::citext) 
+		ON CONFLICT DO NOTHING`, forums, nicknames)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := repository.db.Exec(`DELETE FROM users_forum uf 
+		USING unnest($1::text[], $2::text[]) AS s(forum, nickname) 
+		WHERE uf.slug = s.forum::citext AND uf.nickname = s.nickname::citext 
+		AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.forum = uf.slug AND t.author = uf.nickname) 
+		AND NOT EXISTS (SELECT 1 FROM post p WHERE p.forum = uf.slug AND p.author = uf.nickname)`, forums, nicknames)
+	if err != nil {
+		return 0, err
+	}
+
+	return added.RowsAffected() + removed.RowsAffected(), nil
+}
+
+// repairVoteVoice turns votes stored before the +1/-1 check into the voice
+// their sign stands for, drops the ones without a sign, and recounts the
+// totals of their threads.
+func (repository *Repository) repairVoteVoice(threads []int32, nicknames []string) (int64, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.Exec(`UPDATE vote v SET voice = sign(v.voice) 
+		FROM unnest($1::int[], $2::text[]) AS s(thread, nickname) 
+		WHERE v.thread = s.thread AND v.nickname = s.nickname::citext AND v.voice NOT IN (-1, 0, 1)`, threads, nicknames)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := tx.Exec(`DELETE FROM vote v 
+		USING unnest($1::int[], $2::text[]) AS s(thread, nickname) 
+		WHERE v.thread = s.thread AND v.nickname = s.nickname::citext AND (v.voice IS NULL OR v.voice = 0)`,
+		threads, nicknames)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE thread t 
+		SET votes = COALESCE((SELECT SUM(voice) FROM vote WHERE thread = t.id), 0) 
+		WHERE t.id = ANY($1::int[])`, threads)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated.RowsAffected() + deleted.RowsAffected(), nil
+}
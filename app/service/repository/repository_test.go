@@ -0,0 +1,60 @@
+package servicerepository_test
+
+import (
+	"testing"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	servicerepository "github.com/Kostich31/techpark_db/app/service/repository"
+	"github.com/Kostich31/techpark_db/app/testdb"
+)
+
+func TestCheckRepairsOutOfRangeVoices(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.Exec(t, db, `INSERT INTO users (nickname, fullname, email)
+		SELECT n, n, n || '@example.com' FROM unnest(ARRAY['author', 'a', 'b', 'c']) AS n`)
+	testdb.Exec(t, db, `INSERT INTO forum (title, "user", slug) VALUES ('Forum', 'author', 'forum')`)
+	var threadId int32
+	err := db.QueryRow(`INSERT INTO thread (title, author, forum, message)
+		VALUES ('Thread', 'author', 'forum', 'text') RETURNING id`).Scan(&threadId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rows from before the check was added, stored the way migration 0012
+	// left them.
+	testdb.Exec(t, db, `ALTER TABLE vote DROP CONSTRAINT vote_voice_check`)
+	testdb.Exec(t, db, `INSERT INTO vote (nickname, voice, thread) VALUES ('a', 5, $1), ('b', 0, $1), ('c', -1, $1)`,
+		threadId)
+	testdb.Exec(t, db, `ALTER TABLE vote ADD CONSTRAINT vote_voice_check CHECK (voice IN (-1, 1)) NOT VALID`)
+
+	repository := servicerepository.NewRepository(db)
+	found, err := repository.FindDiscrepancies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Check != domain.CheckVoteVoice || found[1].Check != domain.CheckVoteVoice {
+		t.Fatalf("discrepancies = %+v, want the two votes of a and b", found)
+	}
+
+	repaired, err := repository.Repair(domain.CheckVoteVoice, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired != 2 {
+		t.Errorf("repaired %d votes, want 2", repaired)
+	}
+
+	found, err = repository.FindDiscrepancies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("discrepancies after repair = %+v, want none", found)
+	}
+	var votes int32
+	if err := db.QueryRow(`SELECT votes FROM thread WHERE id = $1`, threadId).Scan(&votes); err != nil {
+		t.Fatal(err)
+	}
+	if votes != 0 {
+		t.Errorf("thread votes = %d, want 0 from a's +1 and c's -1", votes)
+	}
+}
@@ -1,21 +1,115 @@
 package serviceusecase
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/Kostich31/techpark_db/app/domain"
 )
 
+const DefaultCheckBatch = 1000
+
 type UseCase struct {
-	Repository domain.ServiceRepository
+	Repository    domain.ServiceRepository
+	ReadyTimeout  time.Duration
+	SchemaVersion int
+	Build         domain.BuildInfo
+	StartedAt     time.Time
 }
 
-func NewUseCase(repository domain.ServiceRepository) *UseCase {
-	return &UseCase{Repository: repository}
+func NewUseCase(repository domain.ServiceRepository, readyTimeout time.Duration, schemaVersion int,
+	build domain.BuildInfo) *UseCase {
+	return &UseCase{Repository: repository, ReadyTimeout: readyTimeout, SchemaVersion: schemaVersion,
+		Build: build, StartedAt: time.Now()}
 }
 
-func (uc *UseCase) GetStatus() (domain.Status, error) {
-	return uc.Repository.GetStatus()
+func (uc *UseCase) GetStatus(mode string) (domain.Status, error) {
+	switch mode {
+	case domain.StatusModeExact:
+		return uc.Repository.GetStatus()
+	case domain.StatusModeEstimate:
+		return uc.Repository.GetStatusEstimate()
+	case domain.StatusModeCached:
+		return uc.Repository.GetStatusCached()
+	}
+	return domain.Status{}, errors.New(domain.BadStatusMode)
 }
 
 func (uc *UseCase) Clear() error {
 	return uc.Repository.Clear()
 }
+
+// Check reports every aggregate that disagrees with its source rows and, when
+// asked to, repairs them at most batch rows per statement.
+func (uc *UseCase) Check(repair bool, batch int) (domain.CheckReport, error) {
+	found, err := uc.Repository.FindDiscrepancies()
+	if err != nil {
+		return domain.CheckReport{}, err
+	}
+	report := domain.CheckReport{Discrepancies: found}
+	if report.Discrepancies == nil {
+		report.Discrepancies = []domain.Discrepancy{}
+	}
+	if !repair {
+		return report, nil
+	}
+	if batch <= 0 {
+		batch = DefaultCheckBatch
+	}
+
+	for start := 0; start < len(found); {
+		end := start + 1
+		for end < len(found) && end-start < batch && found[end].Check == found[start].Check {
+			end++
+		}
+		repaired, err := uc.Repository.Repair(found[start].Check, found[start:end])
+		if err != nil {
+			return report, err
+		}
+		report.Repaired += repaired
+		start = end
+	}
+
+	return report, nil
+}
+
+func (uc *UseCase) Ready() *domain.CustomError {
+	_, err := uc.checkSchema()
+	return err
+}
+
+func (uc *UseCase) GetHealth() domain.Health {
+	result := domain.Health{
+		Status:                domain.HealthOk,
+		StartedAt:             uc.StartedAt,
+		Uptime:                time.Since(uc.StartedAt).Round(time.Second).String(),
+		ExpectedSchemaVersion: uc.SchemaVersion,
+		Pool:                  uc.Repository.GetPoolStat(),
+		Build:                 uc.Build,
+	}
+
+	version, err := uc.checkSchema()
+	result.SchemaVersion = version
+	if err != nil {
+		result.Status = domain.HealthDegraded
+		result.Error = err.Message
+	}
+
+	return result
+}
+
+func (uc *UseCase) checkSchema() (int, *domain.CustomError) {
+	ctx, cancel := context.WithTimeout(context.Background(), uc.ReadyTimeout)
+	defer cancel()
+
+	version, err := uc.Repository.GetSchemaVersion(ctx)
+	if err != nil {
+		return 0, &domain.CustomError{Message: err.Error()}
+	}
+	if version != uc.SchemaVersion {
+		return version, &domain.CustomError{Message: domain.SchemaMismatch}
+	}
+
+	return version, nil
+}
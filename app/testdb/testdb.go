@@ -0,0 +1,59 @@
+// Package testdb hands tests a migrated, empty database. The database named by
+// FORUM_TEST_DATABASE_URL is wiped on every call, so never point it at real
+// data; tests that need it are skipped while it is unset.
+package testdb
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Kostich31/techpark_db/app/migrate"
+	servicerepository "github.com/Kostich31/techpark_db/app/service/repository"
+	schema "github.com/Kostich31/techpark_db/db"
+	"github.com/jackc/pgx"
+)
+
+const EnvURL = "FORUM_TEST_DATABASE_URL"
+
+func Open(t *testing.T) *pgx.ConnPool {
+	t.Helper()
+	url := os.Getenv(EnvURL)
+	if url == "" {
+		t.Skip(EnvURL + " is not set")
+	}
+
+	cfg, err := pgx.ParseConnectionString(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := pgx.NewConnPool(pgx.ConnPoolConfig{ConnConfig: cfg, MaxConnections: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	migrations, err := migrate.Load(schema.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes, err := migrate.LoadModes(schema.Migrations, "migrations/modes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.NewMigrator(db, migrations, modes).Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := servicerepository.NewRepository(db).Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// Exec runs fixture statements and fails the test on the first error.
+func Exec(t *testing.T, db *pgx.ConnPool, sql string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(sql, args...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,18 +2,21 @@ package threadhandler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/middleware"
 	"github.com/Kostich31/techpark_db/app/tools"
 	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
 	UseCase domain.ThreadUseCase
+	Cursors *tools.CursorSigner
 }
 
-func NewHandler(usecase domain.ThreadUseCase) *Handler {
-	return &Handler{UseCase: usecase}
+func NewHandler(usecase domain.ThreadUseCase, cursors *tools.CursorSigner) *Handler {
+	return &Handler{UseCase: usecase, Cursors: cursors}
 }
 
 func (handler *Handler) CreatePosts(ctx echo.Context) error {
@@ -23,8 +26,27 @@ func (handler *Handler) CreatePosts(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, err.Error())
 	}
 	slugOrId := ctx.Param("slug_or_id")
-	posts, err := handler.UseCase.CreatePosts(slugOrId, newPosts)
+	mode := tools.ParseQueryBatchMode(ctx)
+	posts, rejected, err := handler.UseCase.CreatePosts(slugOrId, newPosts, mode, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.BadBatchMode {
+			return ctx.JSON(http.StatusBadRequest, err)
+		}
+		if err.Message == domain.InvalidPosts {
+			return ctx.JSON(http.StatusUnprocessableEntity, domain.PostBatchError{Message: err.Message, Rejected: rejected})
+		}
+		if err.Message == domain.TooManyPosts {
+			return ctx.JSON(http.StatusRequestEntityTooLarge, err)
+		}
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.UserBanned || err.Message == domain.UserMuted {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
 		if err.Message == domain.NoUser {
 			return ctx.JSON(http.StatusNotFound, err)
 		}
@@ -34,9 +56,21 @@ func (handler *Handler) CreatePosts(ctx echo.Context) error {
 		if err.Message == domain.BadParentPost {
 			return ctx.JSON(http.StatusConflict, err)
 		}
+		if err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadLocked {
+			return ctx.JSON(http.StatusLocked, err)
+		}
 		return ctx.JSON(http.StatusInternalServerError, err)
 	}
 
+	if mode == domain.BatchModePartial {
+		if rejected == nil {
+			rejected = []domain.PostError{}
+		}
+		return ctx.JSON(http.StatusCreated, domain.PostBatch{Posts: posts, Rejected: rejected})
+	}
 	return ctx.JSON(http.StatusCreated, posts)
 }
 
@@ -46,16 +80,78 @@ func (handler *Handler) Vote(ctx echo.Context) error {
 	if err := ctx.Bind(&newVoice); err != nil {
 		return ctx.JSON(http.StatusBadRequest, err.Error())
 	}
+
+	if err := ctx.Validate(&newVoice); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
 	slugOrId := ctx.Param("slug_or_id")
 
-	thread, err := handler.UseCase.CreateVote(slugOrId, newVoice)
+	thread, err := handler.UseCase.CreateVote(slugOrId, newVoice, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.UserBanned || err.Message == domain.UserMuted {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadLocked {
+			return ctx.JSON(http.StatusLocked, err)
+		}
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
 	return ctx.JSON(http.StatusOK, thread)
 }
 
+func (handler *Handler) RetractVote(ctx echo.Context) error {
+	thread, err := handler.UseCase.RetractVote(ctx.Param("slug_or_id"), ctx.QueryParam("nickname"), middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden || err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadLocked {
+			return ctx.JSON(http.StatusLocked, err)
+		}
+		if err.Message == domain.NoUser || err.Message == domain.NoVote {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+func (handler *Handler) GetVotes(ctx echo.Context) error {
+	filter, parseErr := tools.ParseCursorFilterUser(ctx, handler.Cursors)
+	if parseErr != nil {
+		return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: domain.BadCursor})
+	}
+
+	votes, err := handler.UseCase.GetVotes(ctx.Param("slug_or_id"), filter)
+	if err != nil {
+		if err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	if len(votes) != 0 {
+		next, prev := tools.PageCursors(filter.Cursor(), len(votes), filter.Since != tools.SinceParamDefault,
+			tools.Cursor{Since: votes[0].NickName}, tools.Cursor{Since: votes[len(votes)-1].NickName})
+		handler.Cursors.SetLinks(ctx, next, prev)
+	}
+	return ctx.JSON(http.StatusOK, votes)
+}
+
 func (handler *Handler) Details(ctx echo.Context) error {
 	slugOrId := ctx.Param("slug_or_id")
 	thread, err := handler.UseCase.GetThreadDetails(slugOrId)
@@ -67,7 +163,10 @@ func (handler *Handler) Details(ctx echo.Context) error {
 }
 
 func (handler *Handler) GetPosts(ctx echo.Context) error {
-	filter := tools.ParseQueryFilterPost(ctx)
+	filter, parseErr := tools.ParseCursorFilterPost(ctx, handler.Cursors)
+	if parseErr != nil {
+		return ctx.JSON(http.StatusBadRequest, domain.CustomError{Message: domain.BadCursor})
+	}
 	slugOrId := ctx.Param("slug_or_id")
 
 	posts, err := handler.UseCase.GetPosts(slugOrId, filter)
@@ -75,6 +174,21 @@ func (handler *Handler) GetPosts(ctx echo.Context) error {
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
+	if len(posts) != 0 {
+		size := len(posts)
+		if filter.Sort == tools.SortParamParentTree {
+			size = 0
+			for _, post := range posts {
+				if post.Parent == 0 {
+					size++
+				}
+			}
+		}
+		next, prev := tools.PageCursors(filter.Cursor(), size, filter.Since != tools.SinceParamDefault,
+			tools.Cursor{Since: strconv.FormatInt(posts[0].Id, 10)},
+			tools.Cursor{Since: strconv.FormatInt(posts[len(posts)-1].Id, 10)})
+		handler.Cursors.SetLinks(ctx, next, prev)
+	}
 	return ctx.JSON(http.StatusOK, posts)
 }
 
@@ -86,8 +200,20 @@ func (handler *Handler) UpdateThread(ctx echo.Context) error {
 	}
 	slugOrId := ctx.Param("slug_or_id")
 
-	thread, err := handler.UseCase.UpdateThread(slugOrId, newThread)
+	thread, err := handler.UseCase.UpdateThread(slugOrId, newThread, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.BadThreadStatus {
+			return ctx.JSON(http.StatusBadRequest, err)
+		}
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
@@ -106,6 +232,138 @@ func (handler *Handler) GetOnePost(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, post)
 }
 
+func (handler *Handler) VotePost(ctx echo.Context) error {
+	var newVoice domain.Vote
+
+	if err := ctx.Bind(&newVoice); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctx.Validate(&newVoice); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	post, err := handler.UseCase.VotePost(ctx.Param("id"), newVoice, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden || err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.UserBanned || err.Message == domain.UserMuted {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadLocked {
+			return ctx.JSON(http.StatusLocked, err)
+		}
+		if err.Message == domain.NoPost || err.Message == domain.NoUser {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, post)
+}
+
+func (handler *Handler) GetPostHistory(ctx echo.Context) error {
+	history, err := handler.UseCase.GetPostHistory(ctx.Param("id"), middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.NoPost {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, history)
+}
+
+func (handler *Handler) DiffPost(ctx echo.Context) error {
+	diff, err := handler.UseCase.DiffPost(ctx.Param("id"), ctx.QueryParam("from"), ctx.QueryParam("to"),
+		middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.NoPost || err.Message == domain.NoRevision {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, diff)
+}
+
+func (handler *Handler) RevertPost(ctx echo.Context) error {
+	var revert domain.Revert
+
+	if err := ctx.Bind(&revert); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	post, err := handler.UseCase.RevertPost(ctx.Param("id"), revert.Revision, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden || err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoPost || err.Message == domain.NoRevision || err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, post)
+}
+
+func (handler *Handler) AddReaction(ctx echo.Context) error {
+	var reaction domain.Reaction
+
+	if err := ctx.Bind(&reaction); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := ctx.Validate(&reaction); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	post, err := handler.UseCase.AddReaction(ctx.Param("id"), reaction, middleware.CurrentActor(ctx))
+	if err != nil {
+		return handler.reactionError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, post)
+}
+
+func (handler *Handler) RemoveReaction(ctx echo.Context) error {
+	reaction := domain.Reaction{NickName: ctx.QueryParam("nickname"), Emoji: ctx.QueryParam("emoji")}
+
+	post, err := handler.UseCase.RemoveReaction(ctx.Param("id"), reaction, middleware.CurrentActor(ctx))
+	if err != nil {
+		return handler.reactionError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, post)
+}
+
+func (handler *Handler) reactionError(ctx echo.Context, err *domain.CustomError) error {
+	if err.Message == domain.BadReaction {
+		return ctx.JSON(http.StatusBadRequest, err)
+	}
+	if err.Message == domain.Unauthorized {
+		return ctx.JSON(http.StatusUnauthorized, err)
+	}
+	if err.Message == domain.Forbidden || err.Message == domain.ThreadArchived {
+		return ctx.JSON(http.StatusForbidden, err)
+	}
+	if err.Message == domain.UserBanned || err.Message == domain.UserMuted {
+		return ctx.JSON(http.StatusForbidden, err)
+	}
+	if err.Message == domain.ThreadLocked {
+		return ctx.JSON(http.StatusLocked, err)
+	}
+	if err.Message == domain.NoPost || err.Message == domain.NoUser || err.Message == domain.NoReaction {
+		return ctx.JSON(http.StatusNotFound, err)
+	}
+	return ctx.JSON(http.StatusInternalServerError, err)
+}
+
 func (handler *Handler) UpdatePost(ctx echo.Context) error {
 	var postInfo domain.Post
 
@@ -114,10 +372,125 @@ func (handler *Handler) UpdatePost(ctx echo.Context) error {
 	}
 
 	id := ctx.Param("id")
-	post, err := handler.UseCase.UpdatePost(id, postInfo)
+	post, err := handler.UseCase.UpdatePost(id, postInfo, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
 		return ctx.JSON(http.StatusNotFound, err)
 	}
 
 	return ctx.JSON(http.StatusOK, post)
 }
+
+func (handler *Handler) DeletePost(ctx echo.Context) error {
+	id := ctx.Param("id")
+	post, err := handler.UseCase.DeletePost(id, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoPost {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		if err.Message == domain.ThreadArchived {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, post)
+}
+
+func (handler *Handler) PurgePost(ctx echo.Context) error {
+	id := ctx.Param("id")
+	result, err := handler.UseCase.PurgePost(id, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoPost {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (handler *Handler) ArchiveThread(ctx echo.Context) error {
+	return handler.setArchived(ctx, true)
+}
+
+func (handler *Handler) UnarchiveThread(ctx echo.Context) error {
+	return handler.setArchived(ctx, false)
+}
+
+func (handler *Handler) setArchived(ctx echo.Context, archived bool) error {
+	slugOrId := ctx.Param("slug_or_id")
+	thread, err := handler.UseCase.ArchiveThread(slugOrId, archived, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+func (handler *Handler) PinThread(ctx echo.Context) error {
+	var pin domain.Pin
+	if err := ctx.Bind(&pin); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	return handler.setPinned(ctx, true, pin.Order)
+}
+
+func (handler *Handler) UnpinThread(ctx echo.Context) error {
+	return handler.setPinned(ctx, false, 0)
+}
+
+func (handler *Handler) setPinned(ctx echo.Context, pinned bool, order int32) error {
+	slugOrId := ctx.Param("slug_or_id")
+	thread, err := handler.UseCase.PinThread(slugOrId, pinned, order, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+func (handler *Handler) DeleteThread(ctx echo.Context) error {
+	slugOrId := ctx.Param("slug_or_id")
+	err := handler.UseCase.DeleteThread(slugOrId, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -1,6 +1,7 @@
 package threadrepository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strconv"
@@ -19,12 +20,182 @@ func NewRepository(db *pgx.ConnPool) *Repository {
 	return &Repository{db: db}
 }
 
-func (repository *Repository) CreatePosts(threadId int, threadForum string, posts []domain.Post) ([]domain.Post, error) {
-	query := `INSERT INTO post(parent, author, message, thread, forum) VALUES `
-	var values []interface{}
+// postsPerInsert keeps each INSERT well under Postgres' 65535 bind parameter
+// limit at five parameters per post.
+const postsPerInsert = 1000
+
+// postTxOptions runs the post pipeline at read committed: every batch bumps
+// the same forum counter row, so a stricter level would fail most concurrent
+// batches. The thread row is held FOR SHARE instead, so it cannot be archived,
+// locked or deleted before the batch commits.
+var postTxOptions = &pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
+
+const postTxAttempts = 3
+
+// CreatePosts stores the batch only when every post is valid and returns the
+// rejected ones otherwise; with partial set it stores the valid posts anyway.
+func (repository *Repository) CreatePosts(slugOrId string, posts []domain.Post, partial bool,
+	check func(domain.Thread, *domain.Sanction) error) ([]domain.Post, []domain.PostError, error) {
+	var result []domain.Post
+	var invalid []domain.PostError
+	var err error
+	for attempt := 0; attempt < postTxAttempts; attempt++ {
+		result, invalid, err = repository.createPosts(slugOrId, posts, partial, check)
+		if pgErr, ok := err.(pgx.PgError); !ok ||
+			pgErr.Code != domain.PgxSerializationErrorCode && pgErr.Code != domain.PgxDeadlockErrorCode {
+			break
+		}
+	}
+	return result, invalid, err
+}
+
+func (repository *Repository) createPosts(slugOrId string, posts []domain.Post, partial bool,
+	check func(domain.Thread, *domain.Sanction) error) ([]domain.Post, []domain.PostError, error) {
+	tx, err := repository.db.BeginEx(context.Background(), postTxOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var row *pgx.Row
+	id, err := strconv.Atoi(slugOrId)
+	if err != nil {
+		row = tx.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+			FROM thread WHERE slug=$1 FOR SHARE`, slugOrId)
+	} else {
+		row = tx.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
+			FROM thread WHERE id=$1 FOR SHARE`, id)
+	}
+	var thread domain.Thread
+	var nullSlug sql.NullString
+	err = row.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes,
+		&nullSlug, &thread.Created, &thread.Archived, &thread.Status, &thread.Pinned, &thread.PinOrder)
+	if err != nil {
+		return nil, nil, err
+	}
+	thread.Slug = nullSlug.String
+
+	var sanction *domain.Sanction
+	if len(posts) != 0 {
+		authors := make([]string, 0, len(posts))
+		for _, post := range posts {
+			authors = append(authors, post.Author)
+		}
+		sanction, err = activeSanction(tx, thread.Forum, authors)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := check(thread, sanction); err != nil {
+		return nil, nil, err
+	}
+
+	result := []domain.Post{}
 	if len(posts) == 0 {
-		query += fmt.Sprintf(`(0, null, null, %d, '%s')`, threadId, threadForum)
+		return result, nil, nil
+	}
+
+	invalid, err := invalidPosts(tx, int(thread.Id), posts)
+	if err != nil {
+		return nil, nil, err
 	}
+	if len(invalid) != 0 {
+		if !partial {
+			return nil, invalid, nil
+		}
+		rejected := make(map[int]bool, len(invalid))
+		for _, item := range invalid {
+			rejected[item.Index] = true
+		}
+		valid := make([]domain.Post, 0, len(posts)-len(rejected))
+		for i, post := range posts {
+			if !rejected[i] {
+				valid = append(valid, post)
+			}
+		}
+		posts = valid
+	}
+
+	for start := 0; start < len(posts); start += postsPerInsert {
+		end := start + postsPerInsert
+		if end > len(posts) {
+			end = len(posts)
+		}
+		inserted, err := insertPosts(tx, int(thread.Id), thread.Forum, posts[start:end])
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, inserted...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return result, invalid, nil
+}
+
+// activeSanction returns a ban or mute stopping any of nicknames from writing
+// in forum, the way the user repository's GetActiveSanction does, but read
+// inside the batch transaction.
+func activeSanction(tx *pgx.Tx, forum string, nicknames []string) (*domain.Sanction, error) {
+	var sanction domain.Sanction
+	var sanctionForum, issuedBy sql.NullString
+	err := tx.QueryRow(`SELECT id, kind, nickname, forum, reason, issued_by, created, expires FROM (
+			SELECT id, kind, nickname, forum, reason, issued_by, created, expires FROM sanction 
+			WHERE nickname = ANY($2::citext[]) AND (forum IS NULL OR forum = $1) 
+			AND (expires IS NULL OR expires > NOW()) 
+			UNION ALL 
+			SELECT 0, 'mute', nickname, forum, '', NULL, granted, NULL FROM forum_role 
+			WHERE forum = $1 AND role = 'banned' AND nickname = ANY($2::citext[])
+		) active ORDER BY kind, created LIMIT 1`, forum, nicknames).Scan(&sanction.Id, &sanction.Kind,
+		&sanction.Nickname, &sanctionForum, &sanction.Reason, &issuedBy, &sanction.Created, &sanction.Expires)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sanction.Forum = sanctionForum.String
+	sanction.IssuedBy = issuedBy.String
+
+	return &sanction, nil
+}
+
+// invalidPosts lists the posts of a batch whose parent is not a post of the
+// thread or whose author does not exist, parent problems first, mirroring the
+// order in which the insert triggers would fail.
+func invalidPosts(tx *pgx.Tx, threadId int, posts []domain.Post) ([]domain.PostError, error) {
+	parents := make([]int64, 0, len(posts))
+	authors := make([]string, 0, len(posts))
+	for _, post := range posts {
+		parents = append(parents, post.Parent)
+		authors = append(authors, post.Author)
+	}
+
+	var badParents, badAuthors []int64
+	err := tx.QueryRow(`SELECT 
+			(SELECT COALESCE(array_agg(i - 1 ORDER BY i), '{}') FROM unnest($1::bigint[]) WITH ORDINALITY AS p(parent, i) 
+				WHERE p.parent <> 0 AND NOT EXISTS (SELECT 1 FROM post WHERE id = p.parent AND thread = $3)), 
+			(SELECT COALESCE(array_agg(i - 1 ORDER BY i), '{}') FROM unnest($2::text[]) WITH ORDINALITY AS a(nickname, i) 
+				WHERE NOT EXISTS (SELECT 1 FROM users WHERE nickname = a.nickname::citext))`,
+		parents, authors, threadId).Scan(&badParents, &badAuthors)
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []domain.PostError
+	for _, index := range badParents {
+		invalid = append(invalid, domain.PostError{Index: int(index), Message: domain.BadParentPost})
+	}
+	for _, index := range badAuthors {
+		invalid = append(invalid, domain.PostError{Index: int(index), Message: domain.NoUser})
+	}
+	return invalid, nil
+}
+
+func insertPosts(tx *pgx.Tx, threadId int, threadForum string, posts []domain.Post) ([]domain.Post, error) {
+	query := `INSERT INTO post(parent, author, message, thread, forum) VALUES `
+	var values []interface{}
 	for i, post := range posts {
 		value := fmt.Sprintf("($%d, $%d, $%d, $%d, $%d),",
 			i*5+1, i*5+2, i*5+3, i*5+4, i*5+5)
@@ -34,38 +205,36 @@ func (repository *Repository) CreatePosts(threadId int, threadForum string, post
 	query = strings.TrimSuffix(query, ",")
 	query += ` RETURNING id, parent, author, message, isEdited, forum, thread, created;`
 
-	rows, err := repository.db.Query(query, values...)
+	rows, err := tx.Query(query, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	result := []domain.Post{}
-	if len(posts) != 0 {
-		for rows.Next() {
-			var post domain.Post
-			err := rows.Scan(&post.Id, &post.Parent, &post.Author, &post.Message,
-				&post.IsEdited, &post.Forum, &post.Thread, &post.Created)
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, post)
+	result := make([]domain.Post, 0, len(posts))
+	for rows.Next() {
+		var post domain.Post
+		err := rows.Scan(&post.Id, &post.Parent, &post.Author, &post.Message,
+			&post.IsEdited, &post.Forum, &post.Thread, &post.Created)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, post)
+	}
 
-		if rows.Err() != nil {
-			return nil, rows.Err()
-		}
+	if rows.Err() != nil {
+		return nil, rows.Err()
 	}
 	return result, nil
 }
 
 func (repository *Repository) GetThreadBySlug(slug string) (domain.Thread, error) {
 	var result domain.Thread
-	row := repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created 
+	row := repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
 		FROM thread WHERE slug=$1`, slug)
 
 	err := row.Scan(&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes,
-		&result.Slug, &result.Created)
+		&result.Slug, &result.Created, &result.Archived, &result.Status, &result.Pinned, &result.PinOrder)
 	if err != nil {
 		return domain.Thread{}, err
 	}
@@ -75,11 +244,11 @@ func (repository *Repository) GetThreadBySlug(slug string) (domain.Thread, error
 
 func (repository *Repository) GetThreadById(id int) (domain.Thread, error) {
 	var result domain.Thread
-	row := repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created 
+	row := repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
 		FROM thread WHERE id=$1`, id)
 	var nullSlug sql.NullString
 	err := row.Scan(&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes,
-		&nullSlug, &result.Created)
+		&nullSlug, &result.Created, &result.Archived, &result.Status, &result.Pinned, &result.PinOrder)
 	if err != nil {
 		return domain.Thread{}, err
 	}
@@ -140,20 +309,64 @@ func (repository *Repository) UpdateVoteBySlugOrId(slugOrId string, vote domain.
 	return nil
 }
 
+func (repository *Repository) DeleteVote(threadId int, nickname string) error {
+	var voice int
+	err := repository.db.QueryRow(`DELETE FROM vote WHERE thread=$1 AND nickname=$2 RETURNING voice`,
+		threadId, nickname).Scan(&voice)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *Repository) GetVotes(threadId int, filter tools.FilterUser) ([]domain.Vote, error) {
+	var rows *pgx.Rows
+	var err error
+	if filter.Since == tools.SinceParamDefault {
+		rows, err = repository.db.Query(`SELECT nickname, voice FROM vote WHERE thread = $1 
+			ORDER BY nickname COLLATE "C" `+filter.Desc+` LIMIT $2`, threadId, filter.Limit)
+	} else if filter.Desc == tools.SortParamTrue {
+		rows, err = repository.db.Query(`SELECT nickname, voice FROM vote 
+			WHERE thread = $1 AND nickname < ($3 COLLATE "C") 
+			ORDER BY nickname COLLATE "C" DESC LIMIT $2`, threadId, filter.Limit, filter.Since)
+	} else {
+		rows, err = repository.db.Query(`SELECT nickname, voice FROM vote 
+			WHERE thread = $1 AND nickname > ($3 COLLATE "C") 
+			ORDER BY nickname COLLATE "C" ASC LIMIT $2`, threadId, filter.Limit, filter.Since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votes []domain.Vote
+	for rows.Next() {
+		var vote domain.Vote
+		err = rows.Scan(&vote.NickName, &vote.Voice)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
+
 func (repository *Repository) GetThreadBySlugOrId(slugOrId string) (domain.Thread, error) {
 	var result domain.Thread
 	var row *pgx.Row
 	id, err := strconv.Atoi(slugOrId)
 	if err != nil {
-		row = repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created 
+		row = repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
 			FROM thread WHERE slug=$1`, slugOrId)
 	} else {
-		row = repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created 
+		row = repository.db.QueryRow(`SELECT id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order 
 			FROM thread WHERE id=$1`, id)
 	}
 	var nullSlug sql.NullString
 	err = row.Scan(&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes,
-		&nullSlug, &result.Created)
+		&nullSlug, &result.Created, &result.Archived, &result.Status, &result.Pinned, &result.PinOrder)
 	if err != nil {
 		return domain.Thread{}, err
 	}
@@ -171,21 +384,21 @@ func (repository *Repository) GetPostsFlatSlugOrId(slugOrId string, filter tools
 		err = row.Scan(&tmpId)
 		if filter.Since == tools.SinceParamDefault {
 			rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 
 											order by id `+filter.Desc+` limit $2`, tmpId, filter.Limit)
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and id < $2 
 											order by id desc limit $3`, tmpId, filter.Since, filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and id > $2 
 											order by id asc limit $3`, tmpId, filter.Since, filter.Limit)
 			}
@@ -193,21 +406,21 @@ func (repository *Repository) GetPostsFlatSlugOrId(slugOrId string, filter tools
 	} else {
 		if filter.Since == tools.SinceParamDefault {
 			rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 
 											order by id `+filter.Desc+` limit $2`, id, filter.Limit)
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and id < $2 
 											order by id desc limit $3`, id, filter.Since, filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and id > $2 
 											order by id asc limit $3`, id, filter.Since, filter.Limit)
 			}
@@ -229,12 +442,15 @@ func (repository *Repository) GetPostsFlatSlugOrId(slugOrId string, filter tools
 			&post.Author,
 			&post.Message,
 			&post.IsEdited,
+			&post.IsDeleted,
 			&post.Forum,
 			&post.Thread,
-			&post.Created)
+			&post.Created,
+			&post.Score)
 		if err != nil {
 			return nil, err
 		}
+		post.HideDeleted()
 
 		result = append(result, post)
 	}
@@ -252,22 +468,22 @@ func (repository *Repository) GetPostsTreeSlugOrId(slugOrId string, filter tools
 		err = row.Scan(&tmpId)
 		if filter.Since == tools.SinceParamDefault {
 			rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 
 											order by paths `+filter.Desc+`, id `+filter.Desc+` limit $2`, tmpId, filter.Limit)
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and paths < (select paths from post where id=$2) 
 											order by paths desc, id desc limit $3`, tmpId, filter.Since, filter.Limit)
 
 			} else {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and paths > (select paths from post where id=$2) 
 											order by paths asc, id asc limit $3`, tmpId, filter.Since, filter.Limit)
 			}
@@ -275,21 +491,21 @@ func (repository *Repository) GetPostsTreeSlugOrId(slugOrId string, filter tools
 	} else {
 		if filter.Since == tools.SinceParamDefault {
 			rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 
 											order by paths `+filter.Desc+`, id `+filter.Desc+` limit $2`, id, filter.Limit)
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and paths < (select paths from post where id=$2) 
 											order by paths desc, id desc limit $3`, id, filter.Since, filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-											select id, parent, author, message, isEdited, forum,  
-											thread, created from post where 
+											select id, parent, author, message, isEdited, isDeleted, forum,  
+											thread, created, score from post where 
 											thread = $1 and paths > (select paths from post where id=$2) 
 											order by paths asc, id asc limit $3`, id, filter.Since, filter.Limit)
 			}
@@ -311,12 +527,15 @@ func (repository *Repository) GetPostsTreeSlugOrId(slugOrId string, filter tools
 			&post.Author,
 			&post.Message,
 			&post.IsEdited,
+			&post.IsDeleted,
 			&post.Forum,
 			&post.Thread,
-			&post.Created)
+			&post.Created,
+			&post.Score)
 		if err != nil {
 			return nil, err
 		}
+		post.HideDeleted()
 
 		result = append(result, post)
 	}
@@ -335,7 +554,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 		if filter.Since == tools.SinceParamDefault {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 
 					AND parent = 0 ORDER BY id DESC LIMIT $2)
 					ORDER BY paths[1] DESC, paths ASC, id ASC;`,
@@ -343,7 +562,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 					filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 
 					AND parent = 0 ORDER BY id ASC LIMIT $2)
 					ORDER BY paths ASC, id ASC;`,
@@ -353,7 +572,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 
 					AND parent = 0 AND paths[1] <
 					(SELECT paths[1] FROM post WHERE id = $2) ORDER BY id DESC LIMIT $3)
@@ -363,7 +582,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 					filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1
 					AND parent = 0 AND paths[1] >
 					(SELECT paths[1] FROM post WHERE id = $2) ORDER BY id ASC LIMIT $3) 
@@ -377,14 +596,14 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 		if filter.Since == tools.SinceParamDefault {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 AND parent = 0 ORDER BY id DESC LIMIT $2)
 					ORDER BY paths[1] DESC, paths ASC, id ASC;`,
 					id,
 					filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 AND parent = 0 ORDER BY id ASC LIMIT $2)
 					ORDER BY paths ASC, id ASC;`,
 					id,
@@ -393,7 +612,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 		} else {
 			if filter.Desc == tools.SortParamTrue {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 AND parent = 0 AND paths[1] <
 					(SELECT paths[1] FROM post WHERE id = $2) ORDER BY id DESC LIMIT $3)
 					ORDER BY paths[1] DESC, paths ASC, id ASC;`,
@@ -402,7 +621,7 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 					filter.Limit)
 			} else {
 				rows, err = repository.db.Query(`
-					SELECT id, parent, author, message, isEdited, forum, thread, created FROM post
+					SELECT id, parent, author, message, isEdited, isDeleted, forum, thread, created, score FROM post
 					WHERE paths[1] IN (SELECT id FROM post WHERE thread = $1 AND parent = 0 AND paths[1] >
 					(SELECT paths[1] FROM post WHERE id = $2) ORDER BY id ASC LIMIT $3) 
 					ORDER BY paths ASC, id ASC;`,
@@ -428,12 +647,15 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 			&post.Author,
 			&post.Message,
 			&post.IsEdited,
+			&post.IsDeleted,
 			&post.Forum,
 			&post.Thread,
-			&post.Created)
+			&post.Created,
+			&post.Score)
 		if err != nil {
 			return nil, err
 		}
+		post.HideDeleted()
 
 		result = append(result, post)
 	}
@@ -441,6 +663,130 @@ func (repository *Repository) GetPostsParentTreeSlugOrId(slugOrId string, filter
 	return result, err
 }
 
+// GetPostsTopSlugOrId walks the post tree ranking siblings by score, highest
+// first, so a reply never leaves its parent's subtree however well it scores.
+func (repository *Repository) GetPostsTopSlugOrId(slugOrId string, filter tools.FilterPosts) ([]*domain.Post, error) {
+	id, err := strconv.Atoi(slugOrId)
+	if err != nil {
+		var tmpId sql.NullInt64
+		row := repository.db.QueryRow("select id from thread where slug = $1", slugOrId)
+		err = row.Scan(&tmpId)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+		id = int(tmpId.Int64)
+	}
+
+	query := `WITH RECURSIVE ranked AS (
+			SELECT id, ARRAY[-score::bigint, id] AS rank FROM post WHERE thread = $1 AND parent = 0
+			UNION ALL
+			SELECT p.id, r.rank || ARRAY[-p.score::bigint, p.id] FROM post p 
+			JOIN ranked r ON p.thread = $1 AND p.parent = r.id
+		)
+		SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.isDeleted, p.forum, p.thread, p.created, p.score 
+		FROM ranked r JOIN post p ON p.id = r.id `
+	var rows *pgx.Rows
+	if filter.Since == tools.SinceParamDefault {
+		rows, err = repository.db.Query(query+`ORDER BY r.rank `+filter.Desc+` LIMIT $2`, id, filter.Limit)
+	} else if filter.Desc == tools.SortParamTrue {
+		rows, err = repository.db.Query(query+`WHERE r.rank < (SELECT rank FROM ranked WHERE id = $3) 
+			ORDER BY r.rank DESC LIMIT $2`, id, filter.Limit, filter.Since)
+	} else {
+		rows, err = repository.db.Query(query+`WHERE r.rank > (SELECT rank FROM ranked WHERE id = $3) 
+			ORDER BY r.rank ASC LIMIT $2`, id, filter.Limit, filter.Since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*domain.Post
+	for rows.Next() {
+		post := &domain.Post{}
+
+		err = rows.Scan(
+			&post.Id,
+			&post.Parent,
+			&post.Author,
+			&post.Message,
+			&post.IsEdited,
+			&post.IsDeleted,
+			&post.Forum,
+			&post.Thread,
+			&post.Created,
+			&post.Score)
+		if err != nil {
+			return nil, err
+		}
+		post.HideDeleted()
+
+		result = append(result, post)
+	}
+
+	return result, rows.Err()
+}
+
+func (repository *Repository) VotePost(id int, vote domain.Vote) (domain.Post, error) {
+	_, err := repository.db.Exec(`INSERT INTO post_vote (post, nickname, voice) VALUES ($1, $2, $3) 
+		ON CONFLICT (post, nickname) DO UPDATE SET voice = EXCLUDED.voice`,
+		id, vote.NickName, vote.Voice)
+	if err != nil {
+		return domain.Post{}, err
+	}
+
+	return repository.GetPostById(id)
+}
+
+func (repository *Repository) AddReaction(id int, reaction domain.Reaction) error {
+	_, err := repository.db.Exec(`INSERT INTO reaction (post, nickname, emoji) VALUES ($1, $2, $3) 
+		ON CONFLICT DO NOTHING`, id, reaction.NickName, reaction.Emoji)
+	return err
+}
+
+func (repository *Repository) DeleteReaction(id int, reaction domain.Reaction) error {
+	tag, err := repository.db.Exec(`DELETE FROM reaction WHERE post = $1 AND nickname = $2 AND emoji = $3`,
+		id, reaction.NickName, reaction.Emoji)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetReactionCounts aggregates the reactions of a whole page of posts in one
+// query, most used emoji first.
+func (repository *Repository) GetReactionCounts(ids []int64) (map[int64][]domain.ReactionCount, error) {
+	result := make(map[int64][]domain.ReactionCount)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := repository.db.Query(`SELECT post, emoji, COUNT(*) FROM reaction WHERE post = ANY($1) 
+		GROUP BY post, emoji ORDER BY post, COUNT(*) DESC, MIN(created)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post int64
+		var count domain.ReactionCount
+		err = rows.Scan(&post, &count.Emoji, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+		result[post] = append(result[post], count)
+	}
+
+	return result, rows.Err()
+}
+
 func (repository *Repository) UpdateThread(slugOrId string, thread domain.Thread) (domain.Thread, error) {
 	var row *pgx.Row
 	var err error
@@ -450,17 +796,19 @@ func (repository *Repository) UpdateThread(slugOrId string, thread domain.Thread
 			title=COALESCE(NULLIF($1, ''), title), 
 			author=COALESCE(NULLIF($2, ''), author), 
 			forum=COALESCE(NULLIF($3, ''), forum), 
-			message=COALESCE(NULLIF($4, ''), message) 
-			where slug=$5 returning id, title, author, forum, message, votes, slug, created`,
-			thread.Title, thread.Author, thread.Forum, thread.Message, slugOrId)
+			message=COALESCE(NULLIF($4, ''), message),
+			status=COALESCE(NULLIF($6, ''), status) 
+			where slug=$5 returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`,
+			thread.Title, thread.Author, thread.Forum, thread.Message, slugOrId, thread.Status)
 	} else {
 		row = repository.db.QueryRow(`UPDATE thread SET 
 			title=COALESCE(NULLIF($1, ''), title), 
 			author=COALESCE(NULLIF($2, ''), author), 
 			forum=COALESCE(NULLIF($3, ''), forum),
-			message=COALESCE(NULLIF($4, ''), message) 
-			where id=$5 returning id, title, author, forum, message, votes, slug, created`,
-			thread.Title, thread.Author, thread.Forum, thread.Message, id)
+			message=COALESCE(NULLIF($4, ''), message),
+			status=COALESCE(NULLIF($6, ''), status) 
+			where id=$5 returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`,
+			thread.Title, thread.Author, thread.Forum, thread.Message, id, thread.Status)
 	}
 
 	err = row.Scan(
@@ -471,7 +819,8 @@ func (repository *Repository) UpdateThread(slugOrId string, thread domain.Thread
 		&thread.Message,
 		&thread.Votes,
 		&thread.Slug,
-		&thread.Created)
+		&thread.Created,
+		&thread.Archived, &thread.Status, &thread.Pinned, &thread.PinOrder)
 	if err != nil {
 		return domain.Thread{}, err
 	}
@@ -481,39 +830,240 @@ func (repository *Repository) UpdateThread(slugOrId string, thread domain.Thread
 
 func (repository *Repository) GetPostById(id int) (domain.Post, error) {
 	var result domain.Post
-	row := repository.db.QueryRow(`SELECT id, parent, author, message, isEdited,
-		forum, thread, created 
+	row := repository.db.QueryRow(`SELECT id, parent, author, message, isEdited, isDeleted,
+		forum, thread, created, score 
 		FROM post WHERE id=$1`, id)
 
-	err := row.Scan(&result.Id, &result.Parent, &result.Author, &result.Message, &result.IsEdited,
-		&result.Forum, &result.Thread, &result.Created)
+	err := row.Scan(&result.Id, &result.Parent, &result.Author, &result.Message, &result.IsEdited, &result.IsDeleted,
+		&result.Forum, &result.Thread, &result.Created, &result.Score)
 	if err != nil {
 		return domain.Post{}, err
 	}
+	result.HideDeleted()
 	return result, nil
 }
 
-func (repository *Repository) UpdatePost(id int, post domain.Post) (domain.Post, error) {
-	query := repository.db.QueryRow(`UPDATE post SET
+func (repository *Repository) UpdatePost(id int, post domain.Post, editor string) (domain.Post, error) {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return domain.Post{}, err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`SELECT message FROM post WHERE id=$1 AND NOT isDeleted FOR UPDATE`, id).Scan(&previous)
+	if err != nil {
+		return domain.Post{}, err
+	}
+	if previous != post.Message {
+		_, err = tx.Exec(`INSERT INTO post_revision (post, revision, editor, message) 
+			SELECT $1, COALESCE(MAX(revision), 0) + 1, NULLIF($2, ''), $3 FROM post_revision WHERE post = $1`,
+			id, editor, previous)
+		if err != nil {
+			return domain.Post{}, err
+		}
+	}
+
+	query := tx.QueryRow(`UPDATE post SET
 		message=$1,
 		isedited= case when message = $1 then isedited else true end 
-		where id=$2 
-		returning id, parent, author, message, isedited, forum, thread, created`,
+		where id=$2 and not isDeleted
+		returning id, parent, author, message, isedited, forum, thread, created, score`,
 		post.Message, id)
 
-	err := query.Scan(
+	err = query.Scan(
+		&post.Id,
+		&post.Parent,
+		&post.Author,
+		&post.Message,
+		&post.IsEdited,
+		&post.Forum,
+		&post.Thread,
+		&post.Created,
+		&post.Score,
+	)
+	if err != nil {
+		return domain.Post{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.Post{}, err
+	}
+	return post, nil
+}
+
+// GetPostHistory turns the stored edits, which keep the message each one
+// replaced, into the text after every revision, starting from the original.
+func (repository *Repository) GetPostHistory(id int) ([]domain.PostRevision, error) {
+	rows, err := repository.db.Query(`SELECT 0, p.author::text, p.created, 
+			COALESCE((SELECT message FROM post_revision WHERE post = p.id ORDER BY revision LIMIT 1), p.message) 
+		FROM post p WHERE p.id = $1 
+		UNION ALL 
+		SELECT r.revision, COALESCE(r.editor::text, ''), r.edited, 
+			COALESCE(LEAD(r.message) OVER (ORDER BY r.revision), p.message) 
+		FROM post_revision r JOIN post p ON p.id = r.post WHERE r.post = $1 
+		ORDER BY 1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []domain.PostRevision
+	for rows.Next() {
+		var revision domain.PostRevision
+		err = rows.Scan(&revision.Revision, &revision.Editor, &revision.Edited, &revision.Message)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, revision)
+	}
+
+	return history, rows.Err()
+}
+
+// DeletePost tombstones the post and keeps the erased message as a final
+// revision attributed to editor.
+func (repository *Repository) DeletePost(id int, editor string) (domain.Post, error) {
+	var post domain.Post
+	row := repository.db.QueryRow(`WITH target AS (
+			SELECT id, message FROM post WHERE id=$1 AND NOT isDeleted FOR UPDATE
+		), revision AS (
+			INSERT INTO post_revision (post, revision, editor, message) 
+			SELECT t.id, COALESCE((SELECT MAX(revision) FROM post_revision WHERE post = t.id), 0) + 1, 
+				NULLIF($2, ''), t.message 
+			FROM target t
+		)
+		UPDATE post p SET
+		isDeleted = true,
+		message = ''
+		FROM target t where p.id=t.id
+		returning p.id, p.parent, p.author, p.message, p.isEdited, p.isDeleted, p.forum, p.thread, p.created, p.score`,
+		id, editor)
+
+	err := row.Scan(
 		&post.Id,
 		&post.Parent,
 		&post.Author,
 		&post.Message,
 		&post.IsEdited,
+		&post.IsDeleted,
 		&post.Forum,
 		&post.Thread,
 		&post.Created,
+		&post.Score,
 	)
 	if err != nil {
 		return domain.Post{}, err
 	}
+	post.HideDeleted()
 
 	return post, nil
 }
+
+func (repository *Repository) PurgePostSubtree(id int) (int64, error) {
+	tag, err := repository.db.Exec(`
+		WITH target AS (SELECT thread, paths FROM post WHERE id = $1)
+		DELETE FROM post p USING target t
+		WHERE p.thread = t.thread 
+		AND p.paths[1] = t.paths[1] 
+		AND p.paths[1:array_length(t.paths, 1)] = t.paths`,
+		id)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (repository *Repository) SetThreadArchived(slugOrId string, archived bool) (domain.Thread, error) {
+	var result domain.Thread
+	var row *pgx.Row
+	id, err := strconv.Atoi(slugOrId)
+	if err != nil {
+		row = repository.db.QueryRow(`UPDATE thread SET archived=$1 WHERE slug=$2 
+			returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`, archived, slugOrId)
+	} else {
+		row = repository.db.QueryRow(`UPDATE thread SET archived=$1 WHERE id=$2 
+			returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`, archived, id)
+	}
+	var nullSlug sql.NullString
+	err = row.Scan(&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes,
+		&nullSlug, &result.Created, &result.Archived, &result.Status, &result.Pinned, &result.PinOrder)
+	if err != nil {
+		return domain.Thread{}, err
+	}
+	result.Slug = nullSlug.String
+	return result, nil
+}
+
+func (repository *Repository) SetThreadPinned(slugOrId string, pinned bool, order int32) (domain.Thread, error) {
+	var result domain.Thread
+	var row *pgx.Row
+	id, err := strconv.Atoi(slugOrId)
+	if err != nil {
+		row = repository.db.QueryRow(`UPDATE thread SET 
+			pinned = $1, 
+			pin_order = CASE WHEN $1 THEN $2 ELSE 0 END 
+			WHERE slug=$3 
+			returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`,
+			pinned, order, slugOrId)
+	} else {
+		row = repository.db.QueryRow(`UPDATE thread SET 
+			pinned = $1, 
+			pin_order = CASE WHEN $1 THEN $2 ELSE 0 END 
+			WHERE id=$3 
+			returning id, title, author, forum, message, votes, slug, created, archived, status, pinned, pin_order`,
+			pinned, order, id)
+	}
+	var nullSlug sql.NullString
+	err = row.Scan(&result.Id, &result.Title, &result.Author, &result.Forum, &result.Message, &result.Votes,
+		&nullSlug, &result.Created, &result.Archived, &result.Status, &result.Pinned, &result.PinOrder)
+	if err != nil {
+		return domain.Thread{}, err
+	}
+	result.Slug = nullSlug.String
+	return result, nil
+}
+
+func (repository *Repository) DeleteThread(id int) error {
+	tx, err := repository.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var forum, author string
+	err = tx.QueryRow(`SELECT forum, author FROM thread WHERE id=$1 FOR UPDATE`, id).Scan(&forum, &author)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM vote WHERE thread=$1`, id)
+	if err != nil {
+		return err
+	}
+
+	var authors []string
+	err = tx.QueryRow(`WITH deleted AS (DELETE FROM post WHERE thread=$1 RETURNING author)
+		SELECT COALESCE(array_agg(DISTINCT author::text), '{}') FROM deleted`, id).Scan(&authors)
+	if err != nil {
+		return err
+	}
+	authors = append(authors, author)
+
+	_, err = tx.Exec(`DELETE FROM thread WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM users_forum uf 
+		WHERE uf.slug = $1 AND uf.nickname = ANY($2::text[])
+		AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.forum = uf.slug AND t.author = uf.nickname)
+		AND NOT EXISTS (SELECT 1 FROM post p WHERE p.forum = uf.slug AND p.author = uf.nickname)`,
+		forum, authors)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
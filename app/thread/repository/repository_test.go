@@ -0,0 +1,76 @@
+package threadrepository
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/testdb"
+)
+
+func TestCreatePostsBadParentLeavesNoRows(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.Exec(t, db, `INSERT INTO users (nickname, fullname, email) VALUES ('author', 'Author', 'author@example.com')`)
+	testdb.Exec(t, db, `INSERT INTO forum (title, "user", slug) VALUES ('Forum', 'author', 'forum')`)
+	var threadId int
+	err := db.QueryRow(`INSERT INTO thread (title, author, forum, message) 
+		VALUES ('Thread', 'author', 'forum', 'text') RETURNING id`).Scan(&threadId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := make([]domain.Post, 1500)
+	for i := range posts {
+		posts[i] = domain.Post{Author: "author", Message: fmt.Sprintf("post %d", i+1)}
+	}
+	posts[499].Parent = 1 << 40
+
+	repository := NewRepository(db)
+	created, invalid, err := repository.CreatePosts(strconv.Itoa(threadId), posts, false,
+		func(domain.Thread, *domain.Sanction) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 0 {
+		t.Errorf("created %d posts, want none", len(created))
+	}
+	if len(invalid) != 1 || invalid[0].Index != 499 || invalid[0].Message != domain.BadParentPost {
+		t.Errorf("invalid = %+v, want post #500 with a bad parent", invalid)
+	}
+
+	var stored, forumPosts int64
+	err = db.QueryRow(`SELECT (SELECT COUNT(*) FROM post WHERE thread = $1), 
+		(SELECT posts FROM forum WHERE slug = 'forum')`, threadId).Scan(&stored, &forumPosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 || forumPosts != 0 {
+		t.Errorf("stored %d posts and counted %d in the forum, want none", stored, forumPosts)
+	}
+}
+
+func TestPinningKeepsLockState(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.Exec(t, db, `INSERT INTO users (nickname, fullname, email) VALUES ('author', 'Author', 'author@example.com')`)
+	testdb.Exec(t, db, `INSERT INTO forum (title, "user", slug) VALUES ('Forum', 'author', 'forum')`)
+	testdb.Exec(t, db, `INSERT INTO thread (title, author, forum, message, slug, status) 
+		VALUES ('Thread', 'author', 'forum', 'text', 'announcement', 'locked')`)
+
+	repository := NewRepository(db)
+	thread, err := repository.SetThreadPinned("announcement", true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thread.Pinned || thread.PinOrder != 2 || thread.Status != domain.ThreadStatusLocked {
+		t.Errorf("pinned thread = %+v, want pinned with order 2 and still locked", thread)
+	}
+
+	thread, err = repository.SetThreadPinned("announcement", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thread.Pinned || thread.PinOrder != 0 || thread.Status != domain.ThreadStatusLocked {
+		t.Errorf("unpinned thread = %+v, want unpinned and still locked", thread)
+	}
+}
@@ -12,63 +12,104 @@ type UseCase struct {
 	Repository      domain.ThreadRepository
 	RepositoryUser  domain.UserRepository
 	RepositoryForum domain.ForumRepository
+	MaxBatch        int
+	Reactions       []string
+	RequireAuth     bool
 }
 
-func NewUseCase(repository domain.ThreadRepository, userRepository domain.UserRepository, forumRepository domain.ForumRepository) *UseCase {
-	return &UseCase{Repository: repository, RepositoryUser: userRepository, RepositoryForum: forumRepository}
+func NewUseCase(repository domain.ThreadRepository, userRepository domain.UserRepository, forumRepository domain.ForumRepository,
+	maxBatch int, reactions []string, requireAuth bool) *UseCase {
+	return &UseCase{Repository: repository, RepositoryUser: userRepository, RepositoryForum: forumRepository,
+		MaxBatch: maxBatch, Reactions: reactions, RequireAuth: requireAuth}
 }
 
-func (uc *UseCase) CreatePosts(slugOrId string, post []domain.Post) ([]domain.Post, *domain.CustomError) {
-	var thread domain.Thread
-	id, err := strconv.Atoi(slugOrId)
-	if err != nil {
-		thread, err = uc.Repository.GetThreadBySlug(slugOrId)
-		if err != nil {
-			if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
-				return nil, &domain.CustomError{Message: domain.ConflictData}
-			}
-
-			if err == pgx.ErrNoRows {
-				return nil, &domain.CustomError{Message: domain.NoUser}
+func (uc *UseCase) CreatePosts(slugOrId string, post []domain.Post, mode string,
+	actor domain.Actor) ([]domain.Post, []domain.PostError, *domain.CustomError) {
+	switch mode {
+	case domain.BatchModeStrict, domain.BatchModeReport, domain.BatchModePartial:
+	default:
+		return nil, nil, &domain.CustomError{Message: domain.BadBatchMode}
+	}
+	if uc.MaxBatch > 0 && len(post) > uc.MaxBatch {
+		return nil, nil, &domain.CustomError{Message: domain.TooManyPosts}
+	}
+	// Writing for someone else is left to moderators, whose role can only be
+	// resolved once the thread and so the forum are known.
+	forOthers := false
+	for _, item := range post {
+		if err := actor.ActFor(item.Author, uc.RequireAuth); err != nil {
+			if err.Message != domain.Forbidden {
+				return nil, nil, err
 			}
-
-			return nil, &domain.CustomError{Message: err.Error()}
+			forOthers = true
 		}
+	}
 
-	} else {
-		thread, err = uc.Repository.GetThreadById(id)
-		if err != nil {
-			if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
-				return nil, &domain.CustomError{Message: domain.ConflictData}
-			}
-
-			if err == pgx.ErrNoRows {
-				return nil, &domain.CustomError{Message: domain.NoUser}
+	posts, invalid, err := uc.Repository.CreatePosts(slugOrId, post, mode == domain.BatchModePartial, func(thread domain.Thread, sanction *domain.Sanction) error {
+		if thread.Archived {
+			return &domain.CustomError{Message: domain.ThreadArchived}
+		}
+		if thread.Status == domain.ThreadStatusLocked {
+			return &domain.CustomError{Message: domain.ThreadLocked}
+		}
+		if forOthers {
+			if err := uc.requireRole(thread.Forum, actor, domain.RoleModerator); err != nil {
+				return err
 			}
-
-			return nil, &domain.CustomError{Message: err.Error()}
 		}
-	}
-	posts, err := uc.Repository.CreatePosts(int(thread.Id), thread.Forum, post)
+		if sanction != nil {
+			return sanction.Error()
+		}
+		return nil
+	})
 	if err != nil {
+		if customErr, ok := err.(*domain.CustomError); ok {
+			return nil, nil, customErr
+		}
+		if err == pgx.ErrNoRows {
+			return nil, nil, &domain.CustomError{Message: domain.NoUser}
+		}
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
-			return nil, &domain.CustomError{Message: domain.ConflictData}
+			return nil, nil, &domain.CustomError{Message: domain.ConflictData}
 		}
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxBadParentErrorCode {
-			return nil, &domain.CustomError{Message: domain.BadParentPost}
+			return nil, nil, &domain.CustomError{Message: domain.BadParentPost}
 		}
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
-			return nil, &domain.CustomError{Message: domain.NoUser}
+			return nil, nil, &domain.CustomError{Message: domain.NoUser}
 		}
-		return nil, &domain.CustomError{Message: err.Error()}
+		return nil, nil, &domain.CustomError{Message: err.Error()}
+	}
+	if len(invalid) != 0 && mode == domain.BatchModeStrict {
+		return nil, nil, &domain.CustomError{Message: invalid[0].Message}
+	}
+	if len(invalid) != 0 && mode == domain.BatchModeReport {
+		return nil, invalid, &domain.CustomError{Message: domain.InvalidPosts}
 	}
 
-	return posts, nil
+	return posts, invalid, nil
 }
 
-func (uc *UseCase) CreateVote(slugOrId string, vote domain.Vote) (domain.Thread, *domain.CustomError) {
-	var thread domain.Thread
-	err := uc.Repository.CreateVoteBySlugOrId(slugOrId, vote)
+func (uc *UseCase) CreateVote(slugOrId string, vote domain.Vote, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	thread, customErr := uc.checkThreadWritable(slugOrId)
+	if customErr != nil {
+		return domain.Thread{}, customErr
+	}
+	if err := uc.actFor(thread.Forum, vote.NickName, actor); err != nil {
+		return domain.Thread{}, err
+	}
+	if thread.Status == domain.ThreadStatusLocked {
+		return domain.Thread{}, &domain.CustomError{Message: domain.ThreadLocked}
+	}
+	sanction, err := uc.RepositoryUser.GetActiveSanction(thread.Forum, []string{vote.NickName})
+	if err != nil {
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+	if sanction != nil {
+		return domain.Thread{}, sanction.Error()
+	}
+
+	err = uc.Repository.CreateVoteBySlugOrId(slugOrId, vote)
 	if err != nil {
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
 			return domain.Thread{}, &domain.CustomError{Message: domain.NoUser}
@@ -96,6 +137,66 @@ func (uc *UseCase) CreateVote(slugOrId string, vote domain.Vote) (domain.Thread,
 	return thread, nil
 }
 
+func (uc *UseCase) RetractVote(slugOrId string, nickname string, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	if nickname == "" {
+		nickname = actor.Nickname
+	}
+	if nickname == "" {
+		return domain.Thread{}, &domain.CustomError{Message: domain.NoUser}
+	}
+	if err := actor.ActFor(nickname, uc.RequireAuth); err != nil {
+		return domain.Thread{}, err
+	}
+
+	thread, customErr := uc.checkThreadWritable(slugOrId)
+	if customErr != nil {
+		return domain.Thread{}, customErr
+	}
+	if thread.Status == domain.ThreadStatusLocked {
+		return domain.Thread{}, &domain.CustomError{Message: domain.ThreadLocked}
+	}
+
+	err := uc.Repository.DeleteVote(int(thread.Id), nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Thread{}, &domain.CustomError{Message: domain.NoVote}
+		}
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	thread, err = uc.Repository.GetThreadById(int(thread.Id))
+	if err != nil {
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return thread, nil
+}
+
+func (uc *UseCase) GetVotes(slugOrId string, filter tools.FilterUser) ([]domain.Vote, *domain.CustomError) {
+	thread, err := uc.Repository.GetThreadBySlugOrId(slugOrId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &domain.CustomError{Message: domain.NoUser}
+		}
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+
+	votes, err := uc.Repository.GetVotes(int(thread.Id), filter)
+	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+	if votes == nil {
+		return []domain.Vote{}, nil
+	}
+
+	if filter.Backward {
+		for i, j := 0, len(votes)-1; i < j; i, j = i+1, j-1 {
+			votes[i], votes[j] = votes[j], votes[i]
+		}
+	}
+	return votes, nil
+}
+
 func (uc *UseCase) GetThreadDetails(slugOrId string) (domain.Thread, *domain.CustomError) {
 	thread, err := uc.Repository.GetThreadBySlugOrId(slugOrId)
 	if err != nil {
@@ -115,6 +216,8 @@ func (uc *UseCase) GetPosts(slugOrId string, filter tools.FilterPosts) ([]*domai
 		result, err = uc.Repository.GetPostsParentTreeSlugOrId(slugOrId, filter)
 	case tools.SortParamTree:
 		result, err = uc.Repository.GetPostsTreeSlugOrId(slugOrId, filter)
+	case tools.SortParamTop:
+		result, err = uc.Repository.GetPostsTopSlugOrId(slugOrId, filter)
 	}
 	if err != nil {
 		return nil, &domain.CustomError{Message: err.Error()}
@@ -128,10 +231,246 @@ func (uc *UseCase) GetPosts(slugOrId string, filter tools.FilterPosts) ([]*domai
 		return []*domain.Post{}, nil
 	}
 
+	ids := make([]int64, 0, len(result))
+	for _, post := range result {
+		ids = append(ids, post.Id)
+	}
+	counts, err := uc.Repository.GetReactionCounts(ids)
+	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+	for _, post := range result {
+		post.Reactions = counts[post.Id]
+	}
+
+	if filter.Backward {
+		result = reversePage(result, filter.Sort)
+	}
 	return result, nil
 }
 
-func (uc *UseCase) UpdateThread(slugOrId string, thread domain.Thread) (domain.Thread, *domain.CustomError) {
+func (uc *UseCase) DeletePost(id string, actor domain.Actor) (domain.Post, *domain.CustomError) {
+	idNum, err := strconv.Atoi(id)
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	if err := uc.checkPostWritable(idNum, actor); err != nil {
+		return domain.Post{}, err
+	}
+
+	post, err := uc.Repository.DeletePost(idNum, actor.Nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoPost}
+		}
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return post, nil
+}
+
+func (uc *UseCase) PurgePost(id string, actor domain.Actor) (domain.PurgeResult, *domain.CustomError) {
+	idNum, err := strconv.Atoi(id)
+	if err != nil {
+		return domain.PurgeResult{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	post, err := uc.Repository.GetPostById(idNum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.PurgeResult{}, &domain.CustomError{Message: domain.NoPost}
+		}
+		return domain.PurgeResult{}, &domain.CustomError{Message: err.Error()}
+	}
+	// Purging cannot be undone, so moderators are left with soft deletes.
+	if err := uc.requireRole(post.Forum, actor, domain.RoleAdmin); err != nil {
+		return domain.PurgeResult{}, err
+	}
+
+	purged, err := uc.Repository.PurgePostSubtree(idNum)
+	if err != nil {
+		return domain.PurgeResult{}, &domain.CustomError{Message: err.Error()}
+	}
+	if purged == 0 {
+		return domain.PurgeResult{}, &domain.CustomError{Message: domain.NoPost}
+	}
+
+	return domain.PurgeResult{Purged: purged}, nil
+}
+
+func (uc *UseCase) ArchiveThread(slugOrId string, archived bool, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	if err := uc.requireThreadModerator(slugOrId, actor); err != nil {
+		return domain.Thread{}, err
+	}
+
+	thread, err := uc.Repository.SetThreadArchived(slugOrId, archived)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Thread{}, &domain.CustomError{Message: domain.NoSlug}
+		}
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return thread, nil
+}
+
+func (uc *UseCase) PinThread(slugOrId string, pinned bool, order int32, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	if err := uc.requireThreadModerator(slugOrId, actor); err != nil {
+		return domain.Thread{}, err
+	}
+
+	thread, err := uc.Repository.SetThreadPinned(slugOrId, pinned, order)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Thread{}, &domain.CustomError{Message: domain.NoSlug}
+		}
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return thread, nil
+}
+
+func (uc *UseCase) DeleteThread(slugOrId string, actor domain.Actor) *domain.CustomError {
+	thread, err := uc.Repository.GetThreadBySlugOrId(slugOrId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if err := uc.requireRole(thread.Forum, actor, domain.RoleModerator); err != nil {
+		return err
+	}
+
+	err = uc.Repository.DeleteThread(int(thread.Id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (uc *UseCase) checkThreadWritable(slugOrId string) (domain.Thread, *domain.CustomError) {
+	thread, err := uc.Repository.GetThreadBySlugOrId(slugOrId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Thread{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Thread{}, &domain.CustomError{Message: err.Error()}
+	}
+	if thread.Archived {
+		return domain.Thread{}, &domain.CustomError{Message: domain.ThreadArchived}
+	}
+
+	return thread, nil
+}
+
+func (uc *UseCase) checkPostWritable(id int, actor domain.Actor) *domain.CustomError {
+	post, err := uc.Repository.GetPostById(id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoPost}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if post.IsDeleted {
+		return &domain.CustomError{Message: domain.NoPost}
+	}
+
+	thread, err := uc.Repository.GetThreadById(int(post.Thread))
+	if err != nil {
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if thread.Archived {
+		return &domain.CustomError{Message: domain.ThreadArchived}
+	}
+	return uc.actFor(post.Forum, post.Author, actor)
+}
+
+// actFor lets the actor write as nickname in forum, where moderators and
+// above may also write for others.
+func (uc *UseCase) actFor(forum string, nickname string, actor domain.Actor) *domain.CustomError {
+	err := actor.ActFor(nickname, uc.RequireAuth)
+	if err != nil && err.Message == domain.Forbidden {
+		return uc.requireRole(forum, actor, domain.RoleModerator)
+	}
+	return err
+}
+
+func (uc *UseCase) requireThreadModerator(slugOrId string, actor domain.Actor) *domain.CustomError {
+	thread, err := uc.Repository.GetThreadBySlugOrId(slugOrId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return uc.requireRole(thread.Forum, actor, domain.RoleModerator)
+}
+
+func (uc *UseCase) requireRole(forum string, actor domain.Actor, min string) *domain.CustomError {
+	role, err := actor.RoleIn(uc.RepositoryForum, forum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if !domain.RoleAtLeast(role, min) {
+		return &domain.CustomError{Message: domain.Forbidden}
+	}
+
+	return nil
+}
+
+// reversePage restores display order after a backward page step. Parent tree
+// pages reverse whole subtrees and keep each subtree in path order.
+func reversePage(posts []*domain.Post, sort string) []*domain.Post {
+	if sort != tools.SortParamParentTree {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+		return posts
+	}
+
+	var subtrees [][]*domain.Post
+	for _, post := range posts {
+		if post.Parent == 0 || len(subtrees) == 0 {
+			subtrees = append(subtrees, nil)
+		}
+		subtrees[len(subtrees)-1] = append(subtrees[len(subtrees)-1], post)
+	}
+
+	result := make([]*domain.Post, 0, len(posts))
+	for i := len(subtrees) - 1; i >= 0; i-- {
+		result = append(result, subtrees[i]...)
+	}
+	return result
+}
+
+func (uc *UseCase) UpdateThread(slugOrId string, thread domain.Thread, actor domain.Actor) (domain.Thread, *domain.CustomError) {
+	switch thread.Status {
+	case "", domain.ThreadStatusOpen, domain.ThreadStatusLocked:
+	default:
+		return domain.Thread{}, &domain.CustomError{Message: domain.BadThreadStatus}
+	}
+	current, customErr := uc.checkThreadWritable(slugOrId)
+	if customErr != nil {
+		return domain.Thread{}, customErr
+	}
+	if thread.Status != "" {
+		if err := uc.requireRole(current.Forum, actor, domain.RoleModerator); err != nil {
+			return domain.Thread{}, err
+		}
+	} else if err := uc.actFor(current.Forum, current.Author, actor); err != nil {
+		return domain.Thread{}, err
+	}
+
 	thread, err := uc.Repository.UpdateThread(slugOrId, thread)
 	if err != nil {
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
@@ -158,9 +497,14 @@ func (uc *UseCase) GetPost(id string, filter tools.FilterOnePost) (domain.PostIn
 	if err != nil {
 		return domain.PostInfo{}, &domain.CustomError{Message: err.Error()}
 	}
+	counts, err := uc.Repository.GetReactionCounts([]int64{post.Id})
+	if err != nil {
+		return domain.PostInfo{}, &domain.CustomError{Message: err.Error()}
+	}
+	post.Reactions = counts[post.Id]
 	result.Post = post
 
-	if filter.User {
+	if filter.User && !post.IsDeleted {
 		user, err := uc.RepositoryUser.GetUser(post.Author)
 		if err != nil {
 			return domain.PostInfo{}, &domain.CustomError{Message: err.Error()}
@@ -187,7 +531,136 @@ func (uc *UseCase) GetPost(id string, filter tools.FilterOnePost) (domain.PostIn
 	return result, nil
 }
 
-func (uc *UseCase) UpdatePost(id string, post domain.Post) (domain.Post, *domain.CustomError) {
+func (uc *UseCase) VotePost(id string, vote domain.Vote, actor domain.Actor) (domain.Post, *domain.CustomError) {
+	postId, customErr := uc.checkPostOpen(id, vote.NickName, actor)
+	if customErr != nil {
+		return domain.Post{}, customErr
+	}
+
+	post, err := uc.Repository.VotePost(postId, vote)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return post, nil
+}
+
+func (uc *UseCase) AddReaction(id string, reaction domain.Reaction, actor domain.Actor) (domain.Post, *domain.CustomError) {
+	allowed := false
+	for _, emoji := range uc.Reactions {
+		if emoji == reaction.Emoji {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return domain.Post{}, &domain.CustomError{Message: domain.BadReaction}
+	}
+
+	postId, customErr := uc.checkPostOpen(id, reaction.NickName, actor)
+	if customErr != nil {
+		return domain.Post{}, customErr
+	}
+
+	err := uc.Repository.AddReaction(postId, reaction)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return uc.getPostWithReactions(postId)
+}
+
+func (uc *UseCase) RemoveReaction(id string, reaction domain.Reaction, actor domain.Actor) (domain.Post, *domain.CustomError) {
+	if reaction.NickName == "" {
+		reaction.NickName = actor.Nickname
+	}
+	if reaction.NickName == "" {
+		return domain.Post{}, &domain.CustomError{Message: domain.NoUser}
+	}
+	if err := actor.ActFor(reaction.NickName, uc.RequireAuth); err != nil {
+		return domain.Post{}, err
+	}
+
+	postId, err := strconv.Atoi(id)
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: domain.NoPost}
+	}
+	err = uc.Repository.DeleteReaction(postId, reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoReaction}
+		}
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return uc.getPostWithReactions(postId)
+}
+
+func (uc *UseCase) getPostWithReactions(id int) (domain.Post, *domain.CustomError) {
+	post, err := uc.Repository.GetPostById(id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoPost}
+		}
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	counts, err := uc.Repository.GetReactionCounts([]int64{post.Id})
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+	post.Reactions = counts[post.Id]
+	return post, nil
+}
+
+// checkPostOpen lets nickname react to a live post of a thread that is
+// neither archived nor locked, unless they are banned or muted there.
+func (uc *UseCase) checkPostOpen(id string, nickname string, actor domain.Actor) (int, *domain.CustomError) {
+	postId, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, &domain.CustomError{Message: domain.NoPost}
+	}
+	post, err := uc.Repository.GetPostById(postId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, &domain.CustomError{Message: domain.NoPost}
+		}
+		return 0, &domain.CustomError{Message: err.Error()}
+	}
+	if post.IsDeleted {
+		return 0, &domain.CustomError{Message: domain.NoPost}
+	}
+
+	thread, err := uc.Repository.GetThreadById(int(post.Thread))
+	if err != nil {
+		return 0, &domain.CustomError{Message: err.Error()}
+	}
+	if thread.Archived {
+		return 0, &domain.CustomError{Message: domain.ThreadArchived}
+	}
+	if thread.Status == domain.ThreadStatusLocked {
+		return 0, &domain.CustomError{Message: domain.ThreadLocked}
+	}
+	if err := uc.actFor(thread.Forum, nickname, actor); err != nil {
+		return 0, err
+	}
+	sanction, err := uc.RepositoryUser.GetActiveSanction(thread.Forum, []string{nickname})
+	if err != nil {
+		return 0, &domain.CustomError{Message: err.Error()}
+	}
+	if sanction != nil {
+		return 0, sanction.Error()
+	}
+
+	return postId, nil
+}
+
+func (uc *UseCase) UpdatePost(id string, post domain.Post, actor domain.Actor) (domain.Post, *domain.CustomError) {
 	idNum, err := strconv.Atoi(id)
 	if err != nil {
 		return domain.Post{}, &domain.CustomError{Message: err.Error()}
@@ -195,11 +668,122 @@ func (uc *UseCase) UpdatePost(id string, post domain.Post) (domain.Post, *domain
 	if post.Message == "" {
 		post, err = uc.Repository.GetPostById(idNum)
 	} else {
-		post, err = uc.Repository.UpdatePost(idNum, post)
+		if err := uc.checkPostWritable(idNum, actor); err != nil {
+			return domain.Post{}, err
+		}
+		post, err = uc.Repository.UpdatePost(idNum, post, actor.Nickname)
+	}
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return post, nil
+}
+
+// GetPostHistory lists a post's revisions. Deleted posts keep theirs, but
+// only moderators can still see them.
+func (uc *UseCase) GetPostHistory(id string, actor domain.Actor) ([]domain.PostRevision, *domain.CustomError) {
+	idNum, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, &domain.CustomError{Message: domain.NoPost}
+	}
+	post, err := uc.Repository.GetPostById(idNum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &domain.CustomError{Message: domain.NoPost}
+		}
+		return nil, &domain.CustomError{Message: err.Error()}
 	}
+	if post.IsDeleted && uc.requireRole(post.Forum, actor, domain.RoleModerator) != nil {
+		return nil, &domain.CustomError{Message: domain.NoPost}
+	}
+
+	history, err := uc.Repository.GetPostHistory(idNum)
 	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+
+	return history, nil
+}
+
+// DiffPost compares two revisions, by default the latest with the one
+// before it.
+func (uc *UseCase) DiffPost(id string, from string, to string, actor domain.Actor) (domain.PostDiff, *domain.CustomError) {
+	history, customErr := uc.GetPostHistory(id, actor)
+	if customErr != nil {
+		return domain.PostDiff{}, customErr
+	}
+
+	toRevision, customErr := findRevision(history, to, len(history)-1)
+	if customErr != nil {
+		return domain.PostDiff{}, customErr
+	}
+	previous := int(toRevision.Revision) - 1
+	if previous < 0 {
+		previous = 0
+	}
+	fromRevision, customErr := findRevision(history, from, previous)
+	if customErr != nil {
+		return domain.PostDiff{}, customErr
+	}
+
+	return domain.PostDiff{
+		From:  fromRevision.Revision,
+		To:    toRevision.Revision,
+		Lines: tools.DiffLines(fromRevision.Message, toRevision.Message),
+	}, nil
+}
+
+// RevertPost lets moderators restore the text of an earlier revision, which
+// is recorded as a new edit.
+func (uc *UseCase) RevertPost(id string, revision int32, actor domain.Actor) (domain.Post, *domain.CustomError) {
+	history, customErr := uc.GetPostHistory(id, actor)
+	if customErr != nil {
+		return domain.Post{}, customErr
+	}
+	if revision < 0 || int(revision) >= len(history) {
+		return domain.Post{}, &domain.CustomError{Message: domain.NoRevision}
+	}
+
+	idNum, _ := strconv.Atoi(id)
+	post, err := uc.Repository.GetPostById(idNum)
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+	thread, err := uc.Repository.GetThreadById(int(post.Thread))
+	if err != nil {
+		return domain.Post{}, &domain.CustomError{Message: err.Error()}
+	}
+	if thread.Archived {
+		return domain.Post{}, &domain.CustomError{Message: domain.ThreadArchived}
+	}
+	if err := uc.requireRole(post.Forum, actor, domain.RoleModerator); err != nil {
+		return domain.Post{}, err
+	}
+
+	post, err = uc.Repository.UpdatePost(idNum, domain.Post{Message: history[revision].Message}, actor.Nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.Post{}, &domain.CustomError{Message: domain.NoPost}
+		}
 		return domain.Post{}, &domain.CustomError{Message: err.Error()}
 	}
 
 	return post, nil
 }
+
+func findRevision(history []domain.PostRevision, raw string, fallback int) (domain.PostRevision, *domain.CustomError) {
+	index := fallback
+	if raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return domain.PostRevision{}, &domain.CustomError{Message: domain.NoRevision}
+		}
+		index = parsed
+	}
+	if index < 0 || index >= len(history) {
+		return domain.PostRevision{}, &domain.CustomError{Message: domain.NoRevision}
+	}
+
+	return history[index], nil
+}
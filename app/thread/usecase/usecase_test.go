@@ -0,0 +1,155 @@
+package threadusecase
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/jackc/pgx"
+)
+
+type fakeThreads struct {
+	domain.ThreadRepository
+	threads map[int]domain.Thread
+	posts   map[int]domain.Post
+	votes   []domain.Vote
+}
+
+func (repository *fakeThreads) GetThreadBySlugOrId(slugOrId string) (domain.Thread, error) {
+	id, _ := strconv.Atoi(slugOrId)
+	return repository.GetThreadById(id)
+}
+
+func (repository *fakeThreads) CreateVoteBySlugOrId(slugOrId string, vote domain.Vote) error {
+	repository.votes = append(repository.votes, vote)
+	return nil
+}
+
+func (repository *fakeThreads) GetThreadById(id int) (domain.Thread, error) {
+	thread, ok := repository.threads[id]
+	if !ok {
+		return domain.Thread{}, pgx.ErrNoRows
+	}
+	return thread, nil
+}
+
+func (repository *fakeThreads) GetPostById(id int) (domain.Post, error) {
+	post, ok := repository.posts[id]
+	if !ok {
+		return domain.Post{}, pgx.ErrNoRows
+	}
+	return post, nil
+}
+
+func (repository *fakeThreads) UpdatePost(id int, post domain.Post, editor string) (domain.Post, error) {
+	stored, ok := repository.posts[id]
+	if !ok || stored.IsDeleted {
+		return domain.Post{}, pgx.ErrNoRows
+	}
+	stored.Message = post.Message
+	stored.IsEdited = true
+	repository.posts[id] = stored
+	return stored, nil
+}
+
+type fakeUsers struct {
+	domain.UserRepository
+}
+
+func (repository *fakeUsers) GetActiveSanction(forum string, nicknames []string) (*domain.Sanction, error) {
+	return nil, nil
+}
+
+type fakeForums struct {
+	domain.ForumRepository
+	roles map[string]string
+}
+
+func (repository *fakeForums) GetForumRole(forum string, nickname string) (string, error) {
+	if role, ok := repository.roles[nickname]; ok {
+		return role, nil
+	}
+	return domain.RoleMember, nil
+}
+
+func newTestUseCase(requireAuth bool) *UseCase {
+	threads := &fakeThreads{
+		threads: map[int]domain.Thread{1: {Id: 1, Author: "author", Forum: "forum"}},
+		posts: map[int]domain.Post{
+			1: {Id: 1, Author: "author", Forum: "forum", Thread: 1, Message: "text"},
+			2: {Id: 2, Author: "author", Forum: "forum", Thread: 1, IsDeleted: true},
+		},
+	}
+	forums := &fakeForums{roles: map[string]string{"moderator": domain.RoleModerator}}
+	return NewUseCase(threads, &fakeUsers{}, forums, 0, nil, requireAuth)
+}
+
+func message(err *domain.CustomError) string {
+	if err == nil {
+		return ""
+	}
+	return err.Message
+}
+
+func TestUpdatePostAuthPolicy(t *testing.T) {
+	cases := []struct {
+		requireAuth bool
+		actor       domain.Actor
+		want        string
+	}{
+		{false, domain.Actor{}, ""},
+		{false, domain.Actor{Nickname: "author"}, ""},
+		{false, domain.Actor{Nickname: "moderator"}, ""},
+		{false, domain.Actor{Nickname: "other"}, domain.Forbidden},
+		{true, domain.Actor{}, domain.Unauthorized},
+		{true, domain.Actor{Nickname: "author"}, ""},
+		{true, domain.Actor{Nickname: "moderator"}, ""},
+		{true, domain.Actor{Nickname: "other"}, domain.Forbidden},
+		{true, domain.Actor{Admin: true}, ""},
+	}
+	for _, c := range cases {
+		uc := newTestUseCase(c.requireAuth)
+		_, err := uc.UpdatePost("1", domain.Post{Message: "edited"}, c.actor)
+		if got := message(err); got != c.want {
+			t.Errorf("require_auth %v, actor %+v: got %q, want %q", c.requireAuth, c.actor, got, c.want)
+		}
+	}
+}
+
+func TestTombstonesCannotBeEdited(t *testing.T) {
+	for _, actor := range []domain.Actor{{Nickname: "author"}, {Nickname: "moderator"}, {Admin: true}} {
+		uc := newTestUseCase(true)
+		if _, err := uc.UpdatePost("2", domain.Post{Message: "edited"}, actor); message(err) != domain.NoPost {
+			t.Errorf("update by %+v: got %q, want %q", actor, message(err), domain.NoPost)
+		}
+		if _, err := uc.DeletePost("2", actor); message(err) != domain.NoPost {
+			t.Errorf("delete by %+v: got %q, want %q", actor, message(err), domain.NoPost)
+		}
+	}
+}
+
+func TestRetractVoteAnonymousNeedsAuthWhenRequired(t *testing.T) {
+	uc := newTestUseCase(true)
+	_, err := uc.RetractVote("1", "author", domain.Actor{})
+	if got := message(err); got != domain.Unauthorized {
+		t.Errorf("got %q, want %q", got, domain.Unauthorized)
+	}
+}
+
+func TestCreateVoteForOthersTakesModerator(t *testing.T) {
+	cases := []struct {
+		actor domain.Actor
+		want  string
+	}{
+		{domain.Actor{Nickname: "voter"}, ""},
+		{domain.Actor{Nickname: "moderator"}, ""},
+		{domain.Actor{Nickname: "other"}, domain.Forbidden},
+	}
+	for _, c := range cases {
+		uc := newTestUseCase(true)
+		_, err := uc.CreateVote("1", domain.Vote{NickName: "voter", Voice: 1}, c.actor)
+		if got := message(err); got != c.want {
+			t.Errorf("actor %+v: got %q, want %q", c.actor, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	NameCursorParam = "cursor"
+	HeaderLink      = "Link"
+)
+
+var ErrBadCursor = errors.New("bad cursor")
+
+// Cursor is the signed state behind an opaque next/prev page token. Scope
+// binds it to the request path so it cannot be replayed on another list.
+type Cursor struct {
+	Scope    string `json:"p"`
+	Since    string `json:"s"`
+	SinceId  int64  `json:"i,omitempty"`
+	Desc     bool   `json:"d,omitempty"`
+	Sort     string `json:"o,omitempty"`
+	Limit    int    `json:"l"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+type CursorSigner struct {
+	secret []byte
+}
+
+func NewCursorSigner(secret string) *CursorSigner {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	return &CursorSigner{secret: key}
+}
+
+func (signer *CursorSigner) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signer.sign(payload))
+}
+
+func (signer *CursorSigner) Decode(token string) (Cursor, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return Cursor{}, ErrBadCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Cursor{}, ErrBadCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, signer.sign(payload)) {
+		return Cursor{}, ErrBadCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrBadCursor
+	}
+	return cursor, nil
+}
+
+func (signer *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, signer.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Parse returns the cursor passed in the query string of ctx, or false when
+// the request has none.
+func (signer *CursorSigner) Parse(ctx echo.Context) (Cursor, bool, error) {
+	token := ctx.QueryParams().Get(NameCursorParam)
+	if token == "" {
+		return Cursor{}, false, nil
+	}
+
+	cursor, err := signer.Decode(token)
+	if err != nil {
+		return Cursor{}, false, err
+	}
+	if cursor.Scope != ctx.Request().URL.Path {
+		return Cursor{}, false, ErrBadCursor
+	}
+	return cursor, true, nil
+}
+
+// SetLinks writes an RFC 8288 Link header pointing at the neighbouring pages.
+func (signer *CursorSigner) SetLinks(ctx echo.Context, next *Cursor, prev *Cursor) {
+	var links []string
+	for _, link := range []struct {
+		rel    string
+		cursor *Cursor
+	}{{"next", next}, {"prev", prev}} {
+		if link.cursor == nil {
+			continue
+		}
+		link.cursor.Scope = ctx.Request().URL.Path
+
+		query := ctx.Request().URL.Query()
+		query.Del(NameSinceParam)
+		query.Del(NameSinceIdParam)
+		query.Del(NameDescParam)
+		query.Del(NameSortParam)
+		query.Set(NameCursorParam, signer.Encode(*link.cursor))
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, ctx.Request().URL.Path, query.Encode(), link.rel))
+	}
+
+	if len(links) != 0 {
+		ctx.Response().Header().Set(HeaderLink, strings.Join(links, ", "))
+	}
+}
+
+// PageCursors derives the cursors around a page from the keys of its first
+// and last items in display order. base carries the display direction of the
+// request and whether it was itself a backward step.
+func PageCursors(base Cursor, size int, resumed bool, first Cursor, last Cursor) (next *Cursor, prev *Cursor) {
+	if size == 0 {
+		return nil, nil
+	}
+	full := size >= base.Limit
+
+	if full || base.Backward {
+		next = &Cursor{Since: last.Since, SinceId: last.SinceId, Desc: base.Desc, Sort: base.Sort, Limit: base.Limit}
+	}
+	if full && base.Backward || !base.Backward && resumed {
+		prev = &Cursor{Since: first.Since, SinceId: first.SinceId, Desc: base.Desc, Sort: base.Sort,
+			Limit: base.Limit, Backward: true}
+	}
+	return next, prev
+}
+
+func direction(desc bool) string {
+	if desc {
+		return SortParamTrue
+	}
+	return SortParamDefault
+}
+
+func cursorLimit(ctx echo.Context, cursor Cursor, limit int) int {
+	if ctx.QueryParams().Get(NameLimitParam) == "" {
+		return cursor.Limit
+	}
+	return limit
+}
+
+func ParseCursorFilterThread(ctx echo.Context, signer *CursorSigner) (FilterThread, error) {
+	filter := ParseQueryFilterThread(ctx)
+	cursor, ok, err := signer.Parse(ctx)
+	if err != nil || !ok {
+		return filter, err
+	}
+
+	filter.Limit = cursorLimit(ctx, cursor, filter.Limit)
+	filter.Sort = direction(cursor.Desc != cursor.Backward)
+	filter.Since = cursor.Since
+	filter.SinceId = cursor.SinceId
+	filter.Backward = cursor.Backward
+	return filter, nil
+}
+
+func ParseCursorFilterPost(ctx echo.Context, signer *CursorSigner) (FilterPosts, error) {
+	filter := ParseQueryFilterPost(ctx)
+	cursor, ok, err := signer.Parse(ctx)
+	if err != nil || !ok {
+		return filter, err
+	}
+
+	filter.Limit = cursorLimit(ctx, cursor, filter.Limit)
+	filter.Sort = cursor.Sort
+	filter.Desc = direction(cursor.Desc != cursor.Backward)
+	filter.Since = cursor.Since
+	filter.Backward = cursor.Backward
+	return filter, nil
+}
+
+func ParseCursorFilterUser(ctx echo.Context, signer *CursorSigner) (FilterUser, error) {
+	filter := ParseQueryFilterUser(ctx)
+	cursor, ok, err := signer.Parse(ctx)
+	if err != nil || !ok {
+		return filter, err
+	}
+
+	filter.Limit = cursorLimit(ctx, cursor, filter.Limit)
+	filter.Desc = direction(cursor.Desc != cursor.Backward)
+	filter.Since = cursor.Since
+	filter.Backward = cursor.Backward
+	return filter, nil
+}
+
+func ParseCursorFilterSearch(ctx echo.Context, signer *CursorSigner) (FilterSearch, error) {
+	filter := ParseQueryFilterSearch(ctx)
+	cursor, ok, err := signer.Parse(ctx)
+	if err != nil || !ok {
+		return filter, err
+	}
+
+	filter.Limit = cursorLimit(ctx, cursor, filter.Limit)
+	filter.Since = cursor.Since
+	filter.SinceId = cursor.SinceId
+	return filter, nil
+}
+
+func (filter FilterThread) Cursor() Cursor {
+	return Cursor{Desc: (filter.Sort == SortParamTrue) != filter.Backward, Limit: filter.Limit, Backward: filter.Backward}
+}
+
+func (filter FilterPosts) Cursor() Cursor {
+	return Cursor{Desc: (filter.Desc == SortParamTrue) != filter.Backward, Sort: filter.Sort, Limit: filter.Limit,
+		Backward: filter.Backward}
+}
+
+func (filter FilterUser) Cursor() Cursor {
+	return Cursor{Desc: (filter.Desc == SortParamTrue) != filter.Backward, Limit: filter.Limit, Backward: filter.Backward}
+}
+
+func (filter FilterSearch) Cursor() Cursor {
+	return Cursor{Desc: true, Limit: filter.Limit}
+}
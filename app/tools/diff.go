@@ -0,0 +1,93 @@
+package tools
+
+import "strings"
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells bounds the quadratic table below to a couple of megabytes per
+// request; changed regions beyond it are shown as replaced wholesale.
+const maxDiffCells = 1 << 18
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines compares two texts line by line through their longest common
+// subsequence. Edits usually touch a few lines, so the common head and tail
+// are matched first and only the middle goes through the table.
+func DiffLines(from string, to string) []DiffLine {
+	a, b := strings.Split(from, "\n"), strings.Split(to, "\n")
+	result := make([]DiffLine, 0, len(a)+len(b))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result = diffMiddle(result, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return result
+}
+
+func diffMiddle(result []DiffLine, a []string, b []string) []DiffLine {
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			result = append(result, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			result = append(result, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return result
+	}
+
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			result = append(result, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return result
+}
@@ -12,22 +12,38 @@ const (
 	NameSinceParam = "since"
 	NameSortParam = "sort"
 	NameRelatedParam = "related"
+	NameModeParam = "mode"
+	NameSinceIdParam = "since_id"
+	NameQueryParam = "q"
+	NameForumParam = "forum"
+	NameAuthorParam = "author"
+	NameTypeParam = "type"
+	NameIncludeArchivedParam = "include_archived"
 )
 
 const (
 	LimitParamDefault = 100
+	SearchLimitMax = 100
 	SinceParamDefault = ""
 	SortParamDefault  = "asc"
 	SortParamTrue     = "desc"
 	SortParamTree     = "tree"
 	SortParamParentTree = "parent_tree"
+	SortParamTop = "top"
 	SortParamFlatDefault = "flat"
+	StatusModeDefault = "exact"
+	BatchModeDefault = "strict"
+	SearchTypePost = "post"
+	SearchTypeThread = "thread"
 )
 
 type FilterThread struct {
 	Limit int
 	Sort  string
 	Since string
+	SinceId int64
+	Backward bool
+	IncludeArchived bool
 }
 
 type FilterPosts struct {
@@ -35,12 +51,24 @@ type FilterPosts struct {
 	Sort string
 	Since string
 	Desc string
+	Backward bool
 }
 
 type FilterUser struct {
 	Limit int
 	Since string
 	Desc string
+	Backward bool
+}
+
+type FilterSearch struct {
+	Query string
+	Forum string
+	Author string
+	Type string
+	Limit int
+	Since string
+	SinceId int64
 }
 
 type FilterOnePost struct {
@@ -75,6 +103,16 @@ func ParseQueryFilterThread(ctx echo.Context) FilterThread {
 	since := queryParam.Get(NameSinceParam)
 	result.Since = since
 
+	result.IncludeArchived = queryParam.Get(NameIncludeArchivedParam) == "true"
+
+	sinceId := queryParam.Get(NameSinceIdParam)
+	if since != "" && sinceId != "" {
+		sinceIdInt, err := strconv.ParseInt(sinceId, 10, 64)
+		if err == nil {
+			result.SinceId = sinceIdInt
+		}
+	}
+
 	return result
 }
 
@@ -99,6 +137,8 @@ func ParseQueryFilterPost (ctx echo.Context) FilterPosts{
 		result.Sort = SortParamTree
 	case SortParamParentTree:
 		result.Sort = SortParamParentTree
+	case SortParamTop:
+		result.Sort = SortParamTop
 	default:
 		result.Sort = SortParamFlatDefault
 	}
@@ -164,3 +204,52 @@ func ParseQueryFilterOnePost (ctx echo.Context) FilterOnePost {
 
 	return result
 }
+
+func ParseQueryStatusMode(ctx echo.Context) string {
+	mode := ctx.QueryParams().Get(NameModeParam)
+	if mode == "" {
+		return StatusModeDefault
+	}
+
+	return mode
+}
+
+func ParseQueryBatchMode(ctx echo.Context) string {
+	mode := ctx.QueryParams().Get(NameModeParam)
+	if mode == "" {
+		return BatchModeDefault
+	}
+
+	return mode
+}
+
+func ParseQueryFilterSearch(ctx echo.Context) FilterSearch {
+	var result FilterSearch
+	queryParam := ctx.QueryParams()
+
+	limit := queryParam.Get(NameLimitParam)
+	if limit != "" {
+		limitInt, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			result.Limit = 100
+		} else {
+			result.Limit = int(limitInt)
+		}
+	} else {
+		result.Limit = LimitParamDefault
+	}
+	if result.Limit > SearchLimitMax {
+		result.Limit = SearchLimitMax
+	}
+
+	result.Query = strings.TrimSpace(queryParam.Get(NameQueryParam))
+	result.Forum = queryParam.Get(NameForumParam)
+	result.Author = queryParam.Get(NameAuthorParam)
+
+	result.Type = queryParam.Get(NameTypeParam)
+	if result.Type == "" {
+		result.Type = SearchTypePost
+	}
+
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/middleware"
 	"github.com/labstack/echo/v4"
 )
 
@@ -60,8 +61,14 @@ func (handler *Handler) UpdateUser(ctx echo.Context) error {
 	}
 	UserUpdate.Nickname = ctx.Param("nickname")
 
-	user, err := handler.UseCase.UpdateUserProfile(UserUpdate)
+	user, err := handler.UseCase.UpdateUserProfile(UserUpdate, middleware.CurrentActor(ctx))
 	if err != nil {
+		if err.Message == domain.Unauthorized {
+			return ctx.JSON(http.StatusUnauthorized, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
 		if err.Message == domain.NoUser {
 			return ctx.JSON(http.StatusNotFound, err)
 		}
@@ -73,3 +80,75 @@ func (handler *Handler) UpdateUser(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, user)
 }
+
+func (handler *Handler) Ban(ctx echo.Context) error {
+	return handler.sanction(ctx, "")
+}
+
+func (handler *Handler) Mute(ctx echo.Context) error {
+	return handler.sanction(ctx, ctx.Param("slug"))
+}
+
+func (handler *Handler) sanction(ctx echo.Context, forum string) error {
+	var sanction domain.Sanction
+
+	if err := ctx.Bind(&sanction); err != nil {
+		return ctx.JSON(http.StatusBadRequest, err.Error())
+	}
+	sanction.Nickname = ctx.Param("nickname")
+	sanction.Forum = forum
+
+	result, err := handler.UseCase.Sanction(sanction, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.BadExpiry {
+			return ctx.JSON(http.StatusBadRequest, err)
+		}
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoUser || err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, result)
+}
+
+func (handler *Handler) LiftSanction(ctx echo.Context) error {
+	err := handler.UseCase.LiftSanction(ctx.Param("id"), middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSanction {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (handler *Handler) GetBans(ctx echo.Context) error {
+	return handler.getSanctions(ctx, "")
+}
+
+func (handler *Handler) GetMutes(ctx echo.Context) error {
+	return handler.getSanctions(ctx, ctx.Param("slug"))
+}
+
+func (handler *Handler) getSanctions(ctx echo.Context, forum string) error {
+	sanctions, err := handler.UseCase.GetSanctions(forum, middleware.CurrentActor(ctx))
+	if err != nil {
+		if err.Message == domain.Forbidden {
+			return ctx.JSON(http.StatusForbidden, err)
+		}
+		if err.Message == domain.NoSlug {
+			return ctx.JSON(http.StatusNotFound, err)
+		}
+		return ctx.JSON(http.StatusInternalServerError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, sanctions)
+}
@@ -1,6 +1,8 @@
 package userrepository
 
 import (
+	"database/sql"
+
 	"github.com/Kostich31/techpark_db/app/domain"
 	"github.com/jackc/pgx"
 )
@@ -13,9 +15,14 @@ func NewRepository(db *pgx.ConnPool) *Repository {
 	return &Repository{db: db}
 }
 
-func (repository *Repository) AddUser(user domain.User) (domain.User, error) {
-	_, err := repository.db.Exec(`INSERT INTO Users (nickname, fullname, about, email) VALUES ($1, $2, $3, $4)`,
-		user.Nickname, user.FullName, user.About, user.Email)
+// AddUser creates the user and, when a hash is given, its password in the
+// same statement.
+func (repository *Repository) AddUser(user domain.User, passwordHash []byte) (domain.User, error) {
+	_, err := repository.db.Exec(`WITH created AS (
+			INSERT INTO Users (nickname, fullname, about, email) VALUES ($1, $2, $3, $4) RETURNING nickname
+		)
+		INSERT INTO user_password (nickname, hash) SELECT nickname, $5 FROM created WHERE $5::bytea IS NOT NULL`,
+		user.Nickname, user.FullName, user.About, user.Email, passwordHash)
 	if err != nil {
 		return domain.User{}, err
 	}
@@ -78,3 +85,86 @@ func (repository *Repository) GetUsersByNicknameOrEmail(nickname string, email s
 
 	return users, nil
 }
+
+const sanctionColumns = `id, kind, nickname, forum, reason, issued_by, created, expires`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSanction(row scanner) (domain.Sanction, error) {
+	var result domain.Sanction
+	var forum, issuedBy sql.NullString
+	err := row.Scan(&result.Id, &result.Kind, &result.Nickname, &forum, &result.Reason, &issuedBy,
+		&result.Created, &result.Expires)
+	if err != nil {
+		return domain.Sanction{}, err
+	}
+	result.Forum = forum.String
+	result.IssuedBy = issuedBy.String
+	return result, nil
+}
+
+func (repository *Repository) AddSanction(sanction domain.Sanction) (domain.Sanction, error) {
+	row := repository.db.QueryRow(`INSERT INTO sanction (kind, nickname, forum, reason, issued_by, expires) 
+		VALUES ($1, COALESCE((SELECT nickname FROM users WHERE nickname = $2), $2), 
+			COALESCE((SELECT slug FROM forum WHERE slug = NULLIF($3, '')), NULLIF($3, '')), $4, NULLIF($5, ''), $6) 
+		RETURNING `+sanctionColumns,
+		sanction.Kind, sanction.Nickname, sanction.Forum, sanction.Reason, sanction.IssuedBy, sanction.Expires)
+
+	return scanSanction(row)
+}
+
+func (repository *Repository) GetSanction(id int64) (domain.Sanction, error) {
+	return scanSanction(repository.db.QueryRow(`SELECT `+sanctionColumns+` FROM sanction WHERE id = $1`, id))
+}
+
+func (repository *Repository) DeleteSanction(id int64) error {
+	_, err := repository.db.Exec(`DELETE FROM sanction WHERE id = $1`, id)
+	return err
+}
+
+// GetActiveSanctions lists unexpired mutes of a forum, or global bans when
+// forum is empty.
+func (repository *Repository) GetActiveSanctions(forum string) ([]domain.Sanction, error) {
+	rows, err := repository.db.Query(`SELECT `+sanctionColumns+` FROM sanction 
+		WHERE forum IS NOT DISTINCT FROM NULLIF($1, '') AND (expires IS NULL OR expires > NOW()) 
+		ORDER BY created DESC, id DESC`, forum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sanctions []domain.Sanction
+	for rows.Next() {
+		sanction, err := scanSanction(rows)
+		if err != nil {
+			return nil, err
+		}
+		sanctions = append(sanctions, sanction)
+	}
+
+	return sanctions, rows.Err()
+}
+
+// GetActiveSanction returns a ban or mute stopping any of nicknames from
+// writing in forum, counting a "banned" forum role as a permanent mute. It
+// returns nil when they are all free to write.
+func (repository *Repository) GetActiveSanction(forum string, nicknames []string) (*domain.Sanction, error) {
+	sanction, err := scanSanction(repository.db.QueryRow(`SELECT `+sanctionColumns+` FROM (
+			SELECT `+sanctionColumns+` FROM sanction 
+			WHERE nickname = ANY($2::citext[]) AND (forum IS NULL OR forum = $1) 
+			AND (expires IS NULL OR expires > NOW()) 
+			UNION ALL 
+			SELECT 0, 'mute', nickname, forum, '', NULL, granted, NULL FROM forum_role 
+			WHERE forum = $1 AND role = 'banned' AND nickname = ANY($2::citext[])
+		) active ORDER BY kind, created LIMIT 1`, forum, nicknames))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &sanction, nil
+}
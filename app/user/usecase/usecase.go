@@ -1,21 +1,37 @@
 package userusecase
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/Kostich31/techpark_db/app/domain"
 	"github.com/jackc/pgx"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UseCase struct {
-	Repository domain.UserRepository
+	Repository  domain.UserRepository
+	Roles       domain.RoleResolver
+	RequireAuth bool
 }
 
-func NewUseCase(repository domain.UserRepository) *UseCase {
-	return &UseCase{Repository: repository}
+func NewUseCase(repository domain.UserRepository, roles domain.RoleResolver, requireAuth bool) *UseCase {
+	return &UseCase{Repository: repository, Roles: roles, RequireAuth: requireAuth}
 }
 
 func (uc *UseCase) CreateUser(user domain.User) ([]domain.User, error) {
 	var resultArray []domain.User
-	result, err := uc.Repository.AddUser(user)
+	var hash []byte
+	if user.Password != "" {
+		var err error
+		hash, err = bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return []domain.User{{}}, err
+		}
+		user.Password = ""
+	}
+
+	result, err := uc.Repository.AddUser(user, hash)
 	if err != nil {
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
 			result, err1 := uc.Repository.GetUsersByNicknameOrEmail(user.Nickname, user.Email)
@@ -38,7 +54,11 @@ func (uc *UseCase) GetUserProfile(nickname string) (domain.User, *domain.CustomE
 	return user, nil
 }
 
-func (uc *UseCase) UpdateUserProfile(user domain.User) (domain.User, *domain.CustomError) {
+func (uc *UseCase) UpdateUserProfile(user domain.User, actor domain.Actor) (domain.User, *domain.CustomError) {
+	if err := actor.ActFor(user.Nickname, uc.RequireAuth); err != nil {
+		return domain.User{}, err
+	}
+
 	userNew, err := uc.Repository.UpdateUser(user)
 	if err != nil {
 		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxUniqErrorCode {
@@ -52,3 +72,101 @@ func (uc *UseCase) UpdateUserProfile(user domain.User) (domain.User, *domain.Cus
 	}
 	return userNew, nil
 }
+
+// Sanction bans a user site-wide when no forum is given and mutes them in the
+// forum otherwise.
+func (uc *UseCase) Sanction(sanction domain.Sanction, actor domain.Actor) (domain.Sanction, *domain.CustomError) {
+	sanction.Kind = domain.SanctionMute
+	if sanction.Forum == "" {
+		sanction.Kind = domain.SanctionBan
+	}
+	if sanction.Expires != nil && !sanction.Expires.After(time.Now()) {
+		return domain.Sanction{}, &domain.CustomError{Message: domain.BadExpiry}
+	}
+	if err := uc.checkSanctionRights(sanction.Forum, sanction.Nickname, actor); err != nil {
+		return domain.Sanction{}, err
+	}
+	sanction.IssuedBy = actor.Nickname
+
+	result, err := uc.Repository.AddSanction(sanction)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == domain.PgxNoFoundFieldErrorCode {
+			return domain.Sanction{}, &domain.CustomError{Message: domain.NoUser}
+		}
+		return domain.Sanction{}, &domain.CustomError{Message: err.Error()}
+	}
+
+	return result, nil
+}
+
+func (uc *UseCase) LiftSanction(id string, actor domain.Actor) *domain.CustomError {
+	idNum, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return &domain.CustomError{Message: domain.NoSanction}
+	}
+
+	sanction, err := uc.Repository.GetSanction(idNum)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSanction}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+	if err := uc.checkSanctionRights(sanction.Forum, sanction.Nickname, actor); err != nil {
+		return err
+	}
+
+	if err := uc.Repository.DeleteSanction(idNum); err != nil {
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (uc *UseCase) GetSanctions(forum string, actor domain.Actor) ([]domain.Sanction, *domain.CustomError) {
+	if err := uc.checkSanctionRights(forum, "", actor); err != nil {
+		return nil, err
+	}
+
+	sanctions, err := uc.Repository.GetActiveSanctions(forum)
+	if err != nil {
+		return nil, &domain.CustomError{Message: err.Error()}
+	}
+	if sanctions == nil {
+		return []domain.Sanction{}, nil
+	}
+
+	return sanctions, nil
+}
+
+// checkSanctionRights requires a moderator for forum mutes and a site admin
+// for bans, and never lets anyone sanction a peer or a superior.
+func (uc *UseCase) checkSanctionRights(forum string, nickname string, actor domain.Actor) *domain.CustomError {
+	// With no target (listing) only the caller's own rank matters.
+	needed, actorRole, target := domain.RoleModerator, "", domain.RoleBanned
+	var err error
+	if forum == "" {
+		needed = domain.RoleAdmin
+		actorRole, err = actor.SiteRole(uc.Roles)
+		if err == nil && nickname != "" {
+			target, err = uc.Roles.GetSiteRole(nickname)
+		}
+	} else {
+		actorRole, err = actor.RoleIn(uc.Roles, forum)
+		if err == nil && nickname != "" {
+			target, err = uc.Roles.GetForumRole(forum, nickname)
+		}
+	}
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.CustomError{Message: domain.NoSlug}
+		}
+		return &domain.CustomError{Message: err.Error()}
+	}
+
+	if !domain.RoleAtLeast(actorRole, needed) || domain.RoleAtLeast(target, actorRole) {
+		return &domain.CustomError{Message: domain.Forbidden}
+	}
+
+	return nil
+}
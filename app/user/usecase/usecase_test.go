@@ -0,0 +1,42 @@
+package userusecase
+
+import (
+	"testing"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+)
+
+type fakeUsers struct {
+	domain.UserRepository
+}
+
+func (repository *fakeUsers) UpdateUser(user domain.User) (domain.User, error) {
+	return user, nil
+}
+
+func TestUpdateUserProfileAuthPolicy(t *testing.T) {
+	cases := []struct {
+		requireAuth bool
+		actor       domain.Actor
+		want        string
+	}{
+		{false, domain.Actor{}, ""},
+		{false, domain.Actor{Nickname: "user"}, ""},
+		{false, domain.Actor{Nickname: "other"}, domain.Forbidden},
+		{true, domain.Actor{}, domain.Unauthorized},
+		{true, domain.Actor{Nickname: "user"}, ""},
+		{true, domain.Actor{Nickname: "other"}, domain.Forbidden},
+		{true, domain.Actor{Admin: true}, ""},
+	}
+	for _, c := range cases {
+		uc := NewUseCase(&fakeUsers{}, nil, c.requireAuth)
+		_, err := uc.UpdateUserProfile(domain.User{Nickname: "user", About: "about"}, c.actor)
+		got := ""
+		if err != nil {
+			got = err.Message
+		}
+		if got != c.want {
+			t.Errorf("require_auth %v, actor %+v: got %q, want %q", c.requireAuth, c.actor, got, c.want)
+		}
+	}
+}
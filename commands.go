@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Kostich31/techpark_db/app/domain"
+	"github.com/Kostich31/techpark_db/app/migrate"
+	serviceUC "github.com/Kostich31/techpark_db/app/service/usecase"
+)
+
+const usage = `usage:
+  main [flags]                    serve the forum api
+  main [flags] migrate up         apply every pending migration
+  main [flags] migrate down       roll back the latest applied migration
+  main [flags] migrate to N       migrate up or down to version N
+  main [flags] migrate status     list migrations and whether they are applied
+  main [flags] migrate enable M   switch on an optional schema mode (e.g. benchmark)
+  main [flags] migrate disable M  switch an optional schema mode back off
+  main [flags] check [-repair] [-batch N]
+                                  compare trigger-maintained counters with their source rows`
+
+func RunCommand(migrator *migrate.Migrator, service domain.ServiceUseCase, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return RunMigrate(migrator, args[1:])
+	case "check":
+		return RunCheck(service, args[1:])
+	}
+	return errors.New(usage)
+}
+
+func RunCheck(service domain.ServiceUseCase, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "recompute the counters that disagree")
+	batch := fs.Int("batch", serviceUC.DefaultCheckBatch, "rows repaired per statement")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := service.Check(*repair, *batch)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tFORUM\tTHREAD\tPOST\tNICKNAME\tSTORED\tACTUAL")
+	for _, item := range report.Discrepancies {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%d\t%d\n", item.Check, item.Forum, item.Thread, item.Post,
+			item.Nickname, item.Stored, item.Actual)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *repair {
+		fmt.Printf("repaired %d rows\n", report.Repaired)
+		return nil
+	}
+	if len(report.Discrepancies) != 0 {
+		return fmt.Errorf("check: %d discrepancies found", len(report.Discrepancies))
+	}
+	return nil
+}
+
+func RunMigrate(migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		return migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("migrate: bad version %q", args[1])
+		}
+		return migrator.To(version)
+	case "enable", "disable":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		if args[0] == "enable" {
+			return migrator.Enable(args[1])
+		}
+		return migrator.Disable(args[1])
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+		modes, err := migrator.Modes()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "\nMODE\tSTATE\tENABLED AT")
+		for _, mode := range modes {
+			state, enabledAt := "off", "-"
+			if mode.Enabled {
+				state, enabledAt = "on", mode.EnabledAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", mode.Name, state, enabledAt)
+		}
+		return w.Flush()
+	}
+	return errors.New(usage)
+}
@@ -0,0 +1,6 @@
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql migrations/modes/*.sql
+var Migrations embed.FS
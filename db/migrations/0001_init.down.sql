@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS vote CASCADE;
+DROP TABLE IF EXISTS post CASCADE;
+DROP TABLE IF EXISTS users_forum CASCADE;
+DROP TABLE IF EXISTS thread CASCADE;
+DROP TABLE IF EXISTS forum CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+DROP TABLE IF EXISTS stats;
+
+DROP FUNCTION IF EXISTS add_votes();
+DROP FUNCTION IF EXISTS update_thread_votes();
+DROP FUNCTION IF EXISTS new_user_forum();
+DROP FUNCTION IF EXISTS update_paths_post();
+DROP FUNCTION IF EXISTS increment_counter_threads();
+DROP FUNCTION IF EXISTS increment_stats();
+DROP FUNCTION IF EXISTS decrement_stats();
+DROP FUNCTION IF EXISTS reset_stats();
@@ -1,12 +1,5 @@
 CREATE EXTENSION IF NOT EXISTS CITEXT;
 
-DROP TABLE IF EXISTS users CASCADE;
-DROP TABLE IF EXISTS forum CASCADE;
-DROP TABLE IF EXISTS thread CASCADE;
-DROP TABLE IF EXISTS users_forum;
-DROP TABLE IF EXISTS post CASCADE;
-DROP TABLE IF EXISTS vote CASCADE;
-
 CREATE UNLOGGED TABLE users (
                        nickname CITEXT UNIQUE PRIMARY KEY,
                        fullname TEXT NOT NULL,
@@ -179,6 +172,71 @@ CREATE TRIGGER after_insert_thread
     FOR EACH ROW
     EXECUTE PROCEDURE increment_counter_threads();
 
+CREATE UNLOGGED TABLE stats (
+                       name TEXT PRIMARY KEY,
+                       total BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO stats (name) VALUES ('users'), ('forum'), ('thread'), ('post');
+
+CREATE OR REPLACE FUNCTION increment_stats() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE stats
+    SET total = total + (SELECT COUNT(*) FROM inserted)
+    WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION decrement_stats() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE stats
+    SET total = total - (SELECT COUNT(*) FROM deleted)
+    WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION reset_stats() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE stats
+    SET total = 0
+    WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER after_insert_users_stats AFTER INSERT ON users
+    REFERENCING NEW TABLE AS inserted FOR EACH STATEMENT EXECUTE PROCEDURE increment_stats();
+CREATE TRIGGER after_delete_users_stats AFTER DELETE ON users
+    REFERENCING OLD TABLE AS deleted FOR EACH STATEMENT EXECUTE PROCEDURE decrement_stats();
+CREATE TRIGGER after_truncate_users_stats AFTER TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE PROCEDURE reset_stats();
+
+CREATE TRIGGER after_insert_forum_stats AFTER INSERT ON forum
+    REFERENCING NEW TABLE AS inserted FOR EACH STATEMENT EXECUTE PROCEDURE increment_stats();
+CREATE TRIGGER after_delete_forum_stats AFTER DELETE ON forum
+    REFERENCING OLD TABLE AS deleted FOR EACH STATEMENT EXECUTE PROCEDURE decrement_stats();
+CREATE TRIGGER after_truncate_forum_stats AFTER TRUNCATE ON forum
+    FOR EACH STATEMENT EXECUTE PROCEDURE reset_stats();
+
+CREATE TRIGGER after_insert_thread_stats AFTER INSERT ON thread
+    REFERENCING NEW TABLE AS inserted FOR EACH STATEMENT EXECUTE PROCEDURE increment_stats();
+CREATE TRIGGER after_delete_thread_stats AFTER DELETE ON thread
+    REFERENCING OLD TABLE AS deleted FOR EACH STATEMENT EXECUTE PROCEDURE decrement_stats();
+CREATE TRIGGER after_truncate_thread_stats AFTER TRUNCATE ON thread
+    FOR EACH STATEMENT EXECUTE PROCEDURE reset_stats();
+
+CREATE TRIGGER after_insert_post_stats AFTER INSERT ON post
+    REFERENCING NEW TABLE AS inserted FOR EACH STATEMENT EXECUTE PROCEDURE increment_stats();
+CREATE TRIGGER after_delete_post_stats AFTER DELETE ON post
+    REFERENCING OLD TABLE AS deleted FOR EACH STATEMENT EXECUTE PROCEDURE decrement_stats();
+CREATE TRIGGER after_truncate_post_stats AFTER TRUNCATE ON post
+    FOR EACH STATEMENT EXECUTE PROCEDURE reset_stats();
+
 CREATE INDEX IF NOT EXISTS idx_thread_forum ON thread (forum);
 CREATE INDEX IF NOT EXISTS idx_thread_created ON thread (created);
 
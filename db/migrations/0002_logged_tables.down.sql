@@ -0,0 +1,7 @@
+ALTER TABLE stats SET UNLOGGED;
+ALTER TABLE vote SET UNLOGGED;
+ALTER TABLE post SET UNLOGGED;
+ALTER TABLE users_forum SET UNLOGGED;
+ALTER TABLE thread SET UNLOGGED;
+ALTER TABLE forum SET UNLOGGED;
+ALTER TABLE users SET UNLOGGED;
@@ -0,0 +1,7 @@
+ALTER TABLE users SET LOGGED;
+ALTER TABLE forum SET LOGGED;
+ALTER TABLE thread SET LOGGED;
+ALTER TABLE users_forum SET LOGGED;
+ALTER TABLE post SET LOGGED;
+ALTER TABLE vote SET LOGGED;
+ALTER TABLE stats SET LOGGED;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_thread_created ON thread (created);
+DROP INDEX IF EXISTS idx_thread_forum_created_id;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_thread_forum_created_id ON thread (forum, created, id);
+DROP INDEX IF EXISTS idx_thread_created;
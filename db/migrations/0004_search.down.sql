@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS before_write_post_search ON post;
+DROP TRIGGER IF EXISTS before_write_thread_search ON thread;
+DROP FUNCTION IF EXISTS update_post_search();
+DROP FUNCTION IF EXISTS update_thread_search();
+
+ALTER TABLE post DROP COLUMN IF EXISTS search;
+ALTER TABLE thread DROP COLUMN IF EXISTS search;
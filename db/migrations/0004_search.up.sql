@@ -0,0 +1,39 @@
+ALTER TABLE post ADD COLUMN search TSVECTOR;
+ALTER TABLE thread ADD COLUMN search TSVECTOR;
+
+CREATE OR REPLACE FUNCTION update_post_search() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.search := to_tsvector('simple', COALESCE(NEW.message, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER before_write_post_search
+    BEFORE INSERT OR UPDATE OF message
+    ON post
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_post_search();
+
+
+CREATE OR REPLACE FUNCTION update_thread_search() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.search := setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'A') ||
+                  setweight(to_tsvector('simple', COALESCE(NEW.message, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER before_write_thread_search
+    BEFORE INSERT OR UPDATE OF title, message
+    ON thread
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_thread_search();
+
+UPDATE post SET search = to_tsvector('simple', COALESCE(message, ''));
+UPDATE thread SET search = setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+                           setweight(to_tsvector('simple', COALESCE(message, '')), 'B');
+
+CREATE INDEX IF NOT EXISTS idx_post_search ON post USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_thread_search ON thread USING GIN (search);
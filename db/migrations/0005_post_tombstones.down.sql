@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS after_soft_delete_post ON post;
+DROP TRIGGER IF EXISTS after_delete_post ON post;
+DROP FUNCTION IF EXISTS decrement_forum_posts_on_delete();
+
+ALTER TABLE post DROP COLUMN IF EXISTS isDeleted;
@@ -0,0 +1,26 @@
+ALTER TABLE post ADD COLUMN isDeleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE FUNCTION decrement_forum_posts_on_delete() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF (TG_OP = 'UPDATE' AND NEW.isDeleted AND NOT OLD.isDeleted) OR
+       (TG_OP = 'DELETE' AND NOT OLD.isDeleted) THEN
+        UPDATE forum
+        SET posts = posts - 1
+        WHERE slug = OLD.forum;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER after_soft_delete_post
+    AFTER UPDATE OF isDeleted
+    ON post
+    FOR EACH ROW
+    EXECUTE PROCEDURE decrement_forum_posts_on_delete();
+
+CREATE TRIGGER after_delete_post
+    AFTER DELETE
+    ON post
+    FOR EACH ROW
+    EXECUTE PROCEDURE decrement_forum_posts_on_delete();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_post_forum_author;
+DROP INDEX IF EXISTS idx_thread_forum_author;
+
+DROP TRIGGER IF EXISTS after_delete_thread ON thread;
+DROP FUNCTION IF EXISTS decrement_counter_threads();
+
+ALTER TABLE thread DROP COLUMN IF EXISTS archived;
@@ -0,0 +1,20 @@
+ALTER TABLE thread ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE FUNCTION decrement_counter_threads() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE forum
+    SET threads = forum.threads - 1
+    WHERE slug = OLD.forum;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER after_delete_thread
+    AFTER DELETE
+    ON thread
+    FOR EACH ROW
+    EXECUTE PROCEDURE decrement_counter_threads();
+
+CREATE INDEX IF NOT EXISTS idx_post_forum_author ON post (forum, author);
+CREATE INDEX IF NOT EXISTS idx_thread_forum_author ON thread (forum, author);
@@ -0,0 +1,2 @@
+ALTER TABLE thread ADD COLUMN status TEXT NOT NULL DEFAULT 'open'
+    CHECK (status IN ('open', 'locked', 'pinned'));
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_thread_forum_pinned;
+
+ALTER TABLE thread DROP COLUMN IF EXISTS pin_order;
@@ -0,0 +1,3 @@
+ALTER TABLE thread ADD COLUMN pin_order INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_thread_forum_pinned ON thread (forum, pin_order) WHERE status = 'pinned';
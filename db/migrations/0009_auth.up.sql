@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS user_password (
+    nickname CITEXT PRIMARY KEY REFERENCES users (nickname) ON DELETE CASCADE,
+    hash     BYTEA NOT NULL,
+    updated  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS auth_token (
+    hash     BYTEA PRIMARY KEY,
+    nickname CITEXT NOT NULL REFERENCES users (nickname) ON DELETE CASCADE,
+    created  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_token_nickname ON auth_token (nickname);
@@ -0,0 +1,12 @@
+-- Forum owners are derived from forum."user"; site admins are rows without a
+-- forum.
+CREATE TABLE IF NOT EXISTS forum_role (
+    forum    CITEXT REFERENCES forum (slug) ON DELETE CASCADE,
+    nickname CITEXT NOT NULL REFERENCES users (nickname) ON DELETE CASCADE,
+    role     TEXT NOT NULL CHECK (role IN ('admin', 'moderator', 'member', 'banned')),
+    granted  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((forum IS NULL) = (role = 'admin'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_forum_role_forum_nickname ON forum_role (forum, nickname) WHERE forum IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_forum_role_site_admin ON forum_role (nickname) WHERE forum IS NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS sanction (
+    id        BIGSERIAL PRIMARY KEY,
+    kind      TEXT NOT NULL CHECK (kind IN ('ban', 'mute')),
+    nickname  CITEXT NOT NULL REFERENCES users (nickname) ON DELETE CASCADE,
+    forum     CITEXT REFERENCES forum (slug) ON DELETE CASCADE,
+    reason    TEXT NOT NULL DEFAULT '',
+    issued_by CITEXT,
+    created   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires   TIMESTAMP WITH TIME ZONE,
+    CHECK ((forum IS NULL) = (kind = 'ban'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_sanction_nickname ON sanction (nickname, forum);
+CREATE INDEX IF NOT EXISTS idx_sanction_forum ON sanction (forum, created);
@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS after_delete_vote ON vote;
+DROP FUNCTION IF EXISTS remove_votes();
+
+CREATE OR REPLACE FUNCTION update_thread_votes() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF OLD.voice <> NEW.voice THEN
+        UPDATE thread
+        SET votes=(votes + NEW.voice * 2)
+        WHERE id = NEW.thread;
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE vote DROP CONSTRAINT IF EXISTS vote_voice_check;
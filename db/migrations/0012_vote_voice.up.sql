@@ -0,0 +1,31 @@
+-- Votes already stored with another voice are left for the consistency check;
+-- only new and updated rows are held to +1/-1.
+ALTER TABLE vote ADD CONSTRAINT vote_voice_check CHECK (voice IN (-1, 1)) NOT VALID;
+
+CREATE OR REPLACE FUNCTION update_thread_votes() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF OLD.voice <> NEW.voice THEN
+        UPDATE thread
+        SET votes=(votes + NEW.voice - OLD.voice)
+        WHERE id = NEW.thread;
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION remove_votes() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE thread
+    SET votes=(votes - OLD.voice)
+    WHERE id = OLD.thread;
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER after_delete_vote
+    AFTER DELETE
+    ON vote
+    FOR EACH ROW
+    EXECUTE PROCEDURE remove_votes();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_post_thread_parent;
+DROP TABLE IF EXISTS post_vote;
+DROP FUNCTION IF EXISTS update_post_score();
+ALTER TABLE post DROP COLUMN IF EXISTS score;
@@ -0,0 +1,30 @@
+ALTER TABLE post ADD COLUMN score INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_vote (
+    post     BIGINT NOT NULL REFERENCES post (id) ON DELETE CASCADE,
+    nickname CITEXT NOT NULL REFERENCES users (nickname),
+    voice    INT    NOT NULL CHECK (voice IN (-1, 1)),
+    PRIMARY KEY (post, nickname)
+);
+
+CREATE OR REPLACE FUNCTION update_post_score() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE post SET score = score + NEW.voice WHERE id = NEW.post;
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE post SET score = score + NEW.voice - OLD.voice WHERE id = NEW.post;
+    ELSE
+        UPDATE post SET score = score - OLD.voice WHERE id = OLD.post;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER after_change_post_vote
+    AFTER INSERT OR UPDATE OR DELETE
+    ON post_vote
+    FOR EACH ROW
+    EXECUTE PROCEDURE update_post_score();
+
+CREATE INDEX IF NOT EXISTS idx_post_thread_parent ON post (thread, parent);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS reaction (
+    post     BIGINT NOT NULL REFERENCES post (id) ON DELETE CASCADE,
+    nickname CITEXT NOT NULL REFERENCES users (nickname),
+    emoji    TEXT   NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post, emoji, nickname)
+);
@@ -0,0 +1,9 @@
+-- Each row is one edit: who made it, when, and the message it replaced.
+CREATE TABLE IF NOT EXISTS post_revision (
+    post     BIGINT NOT NULL REFERENCES post (id) ON DELETE CASCADE,
+    revision INT    NOT NULL,
+    editor   CITEXT,
+    edited   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    message  TEXT   NOT NULL,
+    PRIMARY KEY (post, revision)
+);
@@ -0,0 +1,28 @@
+UPDATE stats s
+SET total = (SELECT SUM(total) FROM stats t WHERE t.name = s.name)
+WHERE s.shard = 0;
+DELETE FROM stats WHERE shard <> 0;
+
+ALTER TABLE stats DROP CONSTRAINT stats_pkey;
+ALTER TABLE stats DROP COLUMN shard;
+ALTER TABLE stats ADD PRIMARY KEY (name);
+
+CREATE OR REPLACE FUNCTION increment_stats() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE stats
+    SET total = total + (SELECT COUNT(*) FROM inserted)
+    WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION decrement_stats() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE stats
+    SET total = total - (SELECT COUNT(*) FROM deleted)
+    WHERE name = TG_TABLE_NAME;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,44 @@
+-- One counter row per table serialises every writer on that row, so each
+-- table gets 16 rows and a session always adds to the one picked by its
+-- backend pid. Readers sum the shards.
+ALTER TABLE stats DROP CONSTRAINT stats_pkey;
+ALTER TABLE stats ADD COLUMN shard INT NOT NULL DEFAULT 0;
+ALTER TABLE stats ADD PRIMARY KEY (name, shard);
+
+INSERT INTO stats (name, shard)
+SELECT n.name, s.shard
+FROM (SELECT DISTINCT name FROM stats) n,
+     generate_series(1, 15) AS s(shard)
+ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION increment_stats() RETURNS TRIGGER AS
+$$
+DECLARE
+    delta BIGINT;
+BEGIN
+    SELECT COUNT(*) FROM inserted INTO delta;
+    IF delta > 0 THEN
+        UPDATE stats
+        SET total = total + delta
+        WHERE name = TG_TABLE_NAME
+          AND shard = pg_backend_pid() % 16;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION decrement_stats() RETURNS TRIGGER AS
+$$
+DECLARE
+    delta BIGINT;
+BEGIN
+    SELECT COUNT(*) FROM deleted INTO delta;
+    IF delta > 0 THEN
+        UPDATE stats
+        SET total = total - delta
+        WHERE name = TG_TABLE_NAME
+          AND shard = pg_backend_pid() % 16;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
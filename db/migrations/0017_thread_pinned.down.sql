@@ -0,0 +1,12 @@
+-- A pinned and locked thread cannot be told apart in the old schema; it stays
+-- locked and loses its pin.
+ALTER TABLE thread DROP CONSTRAINT IF EXISTS thread_status_check;
+ALTER TABLE thread ADD CONSTRAINT thread_status_check CHECK (status IN ('open', 'locked', 'pinned'));
+
+UPDATE thread SET status = 'pinned' WHERE pinned AND status = 'open';
+UPDATE thread SET pin_order = 0 WHERE pinned AND status <> 'pinned';
+
+DROP INDEX IF EXISTS idx_thread_forum_pinned;
+CREATE INDEX IF NOT EXISTS idx_thread_forum_pinned ON thread (forum, pin_order) WHERE status = 'pinned';
+
+ALTER TABLE thread DROP COLUMN IF EXISTS pinned;
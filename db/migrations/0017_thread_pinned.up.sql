@@ -0,0 +1,10 @@
+-- Pins get their own flag so a pinned thread can also be locked; status is
+-- left to lock state.
+ALTER TABLE thread ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE thread SET pinned = TRUE, status = 'open' WHERE status = 'pinned';
+
+ALTER TABLE thread DROP CONSTRAINT IF EXISTS thread_status_check;
+ALTER TABLE thread ADD CONSTRAINT thread_status_check CHECK (status IN ('open', 'locked'));
+
+DROP INDEX IF EXISTS idx_thread_forum_pinned;
+CREATE INDEX IF NOT EXISTS idx_thread_forum_pinned ON thread (forum, pin_order) WHERE pinned;
@@ -0,0 +1,40 @@
+-- A table can only become permanent once every table it references is,
+-- so tables are converted roots first until none are left.
+DO
+$$
+DECLARE
+    pending   TEXT[];
+    remaining TEXT[];
+    tbl       TEXT;
+BEGIN
+    SELECT COALESCE(array_agg(c.relname::TEXT), '{}')
+    FROM pg_class c
+             JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE n.nspname = current_schema()
+      AND c.relkind = 'r'
+      AND c.relpersistence = 'u'
+    INTO pending;
+
+    WHILE array_length(pending, 1) > 0 LOOP
+        remaining := '{}';
+        FOREACH tbl IN ARRAY pending LOOP
+            IF EXISTS(SELECT 1
+                      FROM pg_constraint con
+                               JOIN pg_class referenced ON referenced.oid = con.confrelid
+                      WHERE con.contype = 'f'
+                        AND con.conrelid = tbl::regclass
+                        AND con.conrelid <> con.confrelid
+                        AND referenced.relpersistence = 'u') THEN
+                remaining := remaining || tbl;
+            ELSE
+                EXECUTE format('ALTER TABLE %I SET LOGGED', tbl);
+            END IF;
+        END LOOP;
+
+        IF remaining = pending THEN
+            RAISE EXCEPTION 'cannot switch tables to logged: %', pending;
+        END IF;
+        pending := remaining;
+    END LOOP;
+END
+$$;
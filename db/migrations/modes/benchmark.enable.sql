@@ -0,0 +1,41 @@
+-- A table can only become unlogged once no permanent table references it,
+-- so tables are converted leaves first until none are left.
+DO
+$$
+DECLARE
+    pending   TEXT[];
+    remaining TEXT[];
+    tbl       TEXT;
+BEGIN
+    SELECT COALESCE(array_agg(c.relname::TEXT), '{}')
+    FROM pg_class c
+             JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE n.nspname = current_schema()
+      AND c.relkind = 'r'
+      AND c.relpersistence = 'p'
+      AND c.relname NOT IN ('schema_migrations', 'schema_modes')
+    INTO pending;
+
+    WHILE array_length(pending, 1) > 0 LOOP
+        remaining := '{}';
+        FOREACH tbl IN ARRAY pending LOOP
+            IF EXISTS(SELECT 1
+                      FROM pg_constraint con
+                               JOIN pg_class referencing ON referencing.oid = con.conrelid
+                      WHERE con.contype = 'f'
+                        AND con.confrelid = tbl::regclass
+                        AND con.conrelid <> con.confrelid
+                        AND referencing.relpersistence = 'p') THEN
+                remaining := remaining || tbl;
+            ELSE
+                EXECUTE format('ALTER TABLE %I SET UNLOGGED', tbl);
+            END IF;
+        END LOOP;
+
+        IF remaining = pending THEN
+            RAISE EXCEPTION 'cannot switch tables to unlogged: %', pending;
+        END IF;
+        pending := remaining;
+    END LOOP;
+END
+$$;
@@ -1,12 +1,29 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
 
+	authHandler "github.com/Kostich31/techpark_db/app/auth/delivery"
+	authRepository "github.com/Kostich31/techpark_db/app/auth/repository"
+	authUC "github.com/Kostich31/techpark_db/app/auth/usecase"
+	"github.com/Kostich31/techpark_db/app/config"
+	"github.com/Kostich31/techpark_db/app/domain"
 	forumHandler "github.com/Kostich31/techpark_db/app/forum/delivery"
 	forumRepository "github.com/Kostich31/techpark_db/app/forum/repository"
 	forumUC "github.com/Kostich31/techpark_db/app/forum/usecase"
+	"github.com/Kostich31/techpark_db/app/middleware"
+	"github.com/Kostich31/techpark_db/app/migrate"
+	"github.com/Kostich31/techpark_db/app/ratelimit"
+	searchHandler "github.com/Kostich31/techpark_db/app/search/delivery"
+	searchRepository "github.com/Kostich31/techpark_db/app/search/repository"
+	searchUC "github.com/Kostich31/techpark_db/app/search/usecase"
 	serviceHandler "github.com/Kostich31/techpark_db/app/service/delivery"
 	serviceRepository "github.com/Kostich31/techpark_db/app/service/repository"
 	serviceUC "github.com/Kostich31/techpark_db/app/service/usecase"
@@ -17,6 +34,7 @@ import (
 	userHandler "github.com/Kostich31/techpark_db/app/user/delivery"
 	userRepository "github.com/Kostich31/techpark_db/app/user/repository"
 	userUC "github.com/Kostich31/techpark_db/app/user/usecase"
+	schema "github.com/Kostich31/techpark_db/db"
 	validator "github.com/go-playground/validator"
 	"github.com/jackc/pgx"
 	_ "github.com/jackc/pgx/stdlib"
@@ -24,62 +42,223 @@ import (
 )
 
 var (
-	router = echo.New()
+	router  = echo.New()
+	version = "dev"
+	commit  = "unknown"
+)
+
+const (
+	ExitOK           = 0
+	ExitServerError  = 1
+	ExitDrainTimeout = 2
 )
 
 func main() {
-	db, err := GetPostgres()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := GetPostgres(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrations, err := migrate.Load(schema.Migrations, "migrations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	modes, err := migrate.LoadModes(schema.Migrations, "migrations/modes")
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrator := migrate.NewMigrator(db, migrations, modes)
+
+	serviceUseCase := serviceUC.NewUseCase(serviceRepository.NewRepository(db),
+		cfg.Server.ReadyTimeout, migrator.Latest(), domain.BuildInfo{Version: version, Commit: commit, GoVersion: runtime.Version()})
+
+	if len(cfg.Args) > 0 {
+		err := RunCommand(migrator, serviceUseCase, cfg.Args)
+		db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cursors := tools.NewCursorSigner(cfg.Server.CursorSecret)
+
+	authUseCase := authUC.NewUseCase(authRepository.NewRepository(db), cfg.Server.TokenTTL)
+	authHandler := authHandler.NewHandler(authUseCase)
 	userHandler := userHandler.NewHandler(userUC.NewUseCase(
-		userRepository.NewRepository(db)))
+		userRepository.NewRepository(db), forumRepository.NewRepository(db), cfg.Server.RequireAuth))
 	forumHandler := forumHandler.NewHandler(forumUC.NewUseCase(
-		forumRepository.NewRepository(db), threadRepository.NewRepository(db)))
+		forumRepository.NewRepository(db), threadRepository.NewRepository(db), userRepository.NewRepository(db),
+		cfg.Server.RequireAuth), cursors)
 	threadHandler := threadHandler.NewHandler(threadUC.NewUseCase(
-		threadRepository.NewRepository(db), userRepository.NewRepository(db), forumRepository.NewRepository(db)))
-	serviceHandler := serviceHandler.NewHandler(serviceUC.NewUseCase(serviceRepository.NewRepository(db)))
+		threadRepository.NewRepository(db), userRepository.NewRepository(db), forumRepository.NewRepository(db),
+		cfg.Server.MaxBatchPosts, cfg.Server.ReactionSet(), cfg.Server.RequireAuth), cursors)
+	searchHandler := searchHandler.NewHandler(searchUC.NewUseCase(searchRepository.NewRepository(db)), cursors)
+	serviceHandler := serviceHandler.NewHandler(serviceUseCase)
+
+	adminOnly := middleware.AdminOnly(cfg.Server.AdminToken)
+	requireUser := middleware.RequireUser(cfg.Server.RequireAuth)
+	limits := ratelimit.NewMemoryStore()
+	limitPosts := RateLimit(limits, "posts", cfg.RateLimit.Posts, middleware.ByCaller, middleware.PerItem)
+	limitThreads := RateLimit(limits, "threads", cfg.RateLimit.Threads, middleware.ByParam("slug"), middleware.PerRequest)
+	limitVotes := RateLimit(limits, "votes", cfg.RateLimit.Votes, middleware.ByCaller, middleware.PerRequest)
 
 	validator := validator.New()
 	router.Validator = tools.NewCustomValidator(validator)
+	router.IPExtractor = IPExtractor(cfg.Server)
+	router.Use(middleware.BodyLimit(int64(cfg.Server.MaxBodyBytes)))
+	router.Use(middleware.Authenticate(authUseCase, cfg.Server.AdminToken))
 
 	router.POST("api/user/:nickname/create", userHandler.SignUpUser)
 	router.GET("api/user/:nickname/profile", userHandler.GetUser)
-	router.POST("api/user/:nickname/profile", userHandler.UpdateUser)
-	router.POST("api/forum/create", forumHandler.CreateForum)
+	router.POST("api/user/:nickname/password", authHandler.SetPassword)
+	router.POST("api/auth/token", authHandler.IssueToken)
+	router.DELETE("api/auth/token", authHandler.RevokeToken)
+	router.POST("api/user/:nickname/profile", userHandler.UpdateUser, requireUser)
+	router.POST("api/user/:nickname/ban", userHandler.Ban, requireUser)
+	router.GET("api/bans", userHandler.GetBans, requireUser)
+	router.POST("api/forum/:slug/mutes/:nickname", userHandler.Mute, requireUser)
+	router.GET("api/forum/:slug/mutes", userHandler.GetMutes, requireUser)
+	router.DELETE("api/sanction/:id", userHandler.LiftSanction, requireUser)
+	router.POST("api/forum/create", forumHandler.CreateForum, requireUser)
 	router.GET("api/forum/:slug/details", forumHandler.GetForumDetails)
-	router.POST("api/forum/:slug/create", forumHandler.CreateThread)
+	router.POST("api/forum/:slug/create", forumHandler.CreateThread, requireUser, limitThreads)
 	router.GET("api/forum/:slug/users", forumHandler.GetUsersForum)
 	router.GET("api/forum/:slug/threads", forumHandler.GetForumThreads)
-	router.POST("api/thread/:slug_or_id/create", threadHandler.CreatePosts)
-	router.POST("api/thread/:slug_or_id/vote", threadHandler.Vote)
+	router.GET("api/forum/:slug/roles", forumHandler.GetRoles)
+	router.PUT("api/forum/:slug/roles/:nickname", forumHandler.GrantRole, requireUser)
+	router.DELETE("api/forum/:slug/roles/:nickname", forumHandler.RevokeRole, requireUser)
+	router.PUT("api/admin/:nickname", forumHandler.GrantSiteAdmin, adminOnly)
+	router.DELETE("api/admin/:nickname", forumHandler.RevokeSiteAdmin, adminOnly)
+	router.POST("api/thread/:slug_or_id/create", threadHandler.CreatePosts, requireUser, limitPosts)
+	router.POST("api/thread/:slug_or_id/vote", threadHandler.Vote, requireUser, limitVotes)
+	router.DELETE("api/thread/:slug_or_id/vote", threadHandler.RetractVote, requireUser)
+	router.GET("api/thread/:slug_or_id/votes", threadHandler.GetVotes)
 	router.GET("api/thread/:slug_or_id/details", threadHandler.Details)
 	router.GET("api/thread/:slug_or_id/posts", threadHandler.GetPosts)
-	router.POST("api/thread/:slug_or_id/details", threadHandler.UpdateThread)
+	router.POST("api/thread/:slug_or_id/details", threadHandler.UpdateThread, requireUser)
+	router.POST("api/thread/:slug_or_id/archive", threadHandler.ArchiveThread, requireUser)
+	router.POST("api/thread/:slug_or_id/unarchive", threadHandler.UnarchiveThread, requireUser)
+	router.POST("api/thread/:slug_or_id/pin", threadHandler.PinThread, requireUser)
+	router.POST("api/thread/:slug_or_id/unpin", threadHandler.UnpinThread, requireUser)
+	router.DELETE("api/thread/:slug_or_id", threadHandler.DeleteThread, requireUser)
 	router.GET("api/post/:id/details", threadHandler.GetOnePost)
-	router.POST("api/post/:id/details", threadHandler.UpdatePost)
+	router.POST("api/post/:id/details", threadHandler.UpdatePost, requireUser)
+	router.POST("api/post/:id/vote", threadHandler.VotePost, requireUser, limitVotes)
+	router.GET("api/post/:id/history", threadHandler.GetPostHistory)
+	router.GET("api/post/:id/history/diff", threadHandler.DiffPost)
+	router.POST("api/post/:id/history/revert", threadHandler.RevertPost, requireUser)
+	router.POST("api/post/:id/reactions", threadHandler.AddReaction, requireUser)
+	router.DELETE("api/post/:id/reactions", threadHandler.RemoveReaction, requireUser)
+	router.DELETE("api/post/:id", threadHandler.DeletePost, requireUser)
+	router.DELETE("api/post/:id/subtree", threadHandler.PurgePost, requireUser)
+	router.GET("api/search", searchHandler.Search)
 	router.GET("api/service/status", serviceHandler.Status)
 	router.POST("api/service/clear", serviceHandler.Clear)
-	if err := router.Start(":5000"); err != nil {
+	router.GET("api/service/health", serviceHandler.Health)
+	router.GET("api/service/check", serviceHandler.Check, adminOnly)
+	router.POST("api/service/check/repair", serviceHandler.Repair, adminOnly)
+	router.GET("healthz", serviceHandler.Healthz)
+	router.GET("readyz", serviceHandler.Readyz)
+	server := &http.Server{
+		Addr:         cfg.Server.Address,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- router.StartServer(server)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		db.Close()
+		log.Print(err)
+		os.Exit(ExitServerError)
+	case sig := <-signals:
+		log.Printf("received %s, draining requests for up to %s", sig, cfg.Server.ShutdownTimeout)
+		os.Exit(Shutdown(server, db, cfg.Server.ShutdownTimeout))
+	}
+}
+
+// Closer is the part of the connection pool Shutdown needs.
+type Closer interface {
+	Close()
+}
+
+func Shutdown(server *http.Server, db Closer, timeout time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	code := ExitOK
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %s", err)
+		code = ExitDrainTimeout
+		if err := server.Close(); err != nil {
+			log.Printf("close: %s", err)
+		}
+	}
+	db.Close()
+
+	return code
+}
+
+func RateLimit(store ratelimit.Store, scope string, raw string, key middleware.KeyFunc, cost middleware.CostFunc) echo.MiddlewareFunc {
+	limit, err := ratelimit.ParseLimit(raw)
+	if err != nil {
 		log.Fatal(err)
 	}
+	return middleware.RateLimit(store, scope, limit, key, cost)
 }
 
-func GetPostgres() (*pgx.ConnPool, error) {
-	dsn := fmt.Sprintf("user=%s dbname=%s password=%s host=%s port=%s sslmode=disable",
-		"kostya", "kostya",
-		"kostya", "127.0.0.1",
-		"5432")
-	db, err := pgx.ParseConnectionString(dsn)
+// IPExtractor reads the client address from the connection unless trusted
+// proxies are configured, in which case only their X-Forwarded-For entries
+// are believed.
+func IPExtractor(cfg config.Server) echo.IPExtractor {
+	ranges, err := cfg.TrustedProxyRanges()
 	if err != nil {
-		return nil, err
+		log.Fatal(err)
+	}
+	if len(ranges) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range ranges {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+func GetPostgres(cfg config.Database) (*pgx.ConnPool, error) {
+	db := pgx.ConnConfig{
+		Host:     cfg.Host,
+		Port:     uint16(cfg.Port),
+		Database: cfg.Name,
+		User:     cfg.User,
+		Password: cfg.Password,
 	}
 	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{
 		ConnConfig:     db,
-		MaxConnections: 100,
+		MaxConnections: cfg.MaxConnections,
 		AfterConnect:   nil,
-		AcquireTimeout: 0,
+		AcquireTimeout: cfg.AcquireTimeout,
 	})
 	if err != nil {
 		log.Fatalf("Error %s occurred during connection to database", err)
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakePool struct {
+	closed bool
+}
+
+func (pool *fakePool) Close() {
+	pool.closed = true
+}
+
+// serve runs a server whose only handler signals started and then sleeps for
+// delay, and sends the result of one request to it on the returned channel.
+func serve(t *testing.T, delay time.Duration) (*http.Server, <-chan *http.Response, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+
+	responses := make(chan *http.Response, 1)
+	errs := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			errs <- err
+			return
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		responses <- resp
+	}()
+
+	select {
+	case <-started:
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request never reached the handler")
+	}
+	return server, responses, errs
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	server, responses, errs := serve(t, 200*time.Millisecond)
+	pool := &fakePool{}
+
+	if code := Shutdown(server, pool, 5*time.Second); code != ExitOK {
+		t.Errorf("exit code %d, want %d", code, ExitOK)
+	}
+	if !pool.closed {
+		t.Error("pool was not closed")
+	}
+
+	select {
+	case resp := <-responses:
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+	case err := <-errs:
+		t.Fatalf("in-flight request failed: %s", err)
+	}
+}
+
+func TestShutdownReportsDrainTimeout(t *testing.T) {
+	server, _, _ := serve(t, 2*time.Second)
+	pool := &fakePool{}
+
+	if code := Shutdown(server, pool, 50*time.Millisecond); code != ExitDrainTimeout {
+		t.Errorf("exit code %d, want %d", code, ExitDrainTimeout)
+	}
+	if !pool.closed {
+		t.Error("pool was not closed")
+	}
+}